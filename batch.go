@@ -0,0 +1,50 @@
+package optimus
+
+// BatchTable is a Table that sends its Rows in batches, so that a pipeline makes one channel send
+// per batch instead of one per Row. Once a batch is sent, it belongs to the receiver, which may
+// modify it and the Rows in it. See Batched and Unbatched for converting between Tables and
+// BatchTables. Unlike transforms.Batch, which sends each batch as a Row of a Table, with the
+// batch's Rows in its rows field, a BatchTable sends the batches themselves.
+type BatchTable = TypedTable[[]Row]
+
+// BatchTransformFunc is a TransformFunc that receives and sends batches of Rows. It may send
+// batches of any size, but it shouldn't send empty ones.
+type BatchTransformFunc = TypedTransformFunc[[]Row, []Row]
+
+// TransformBatches returns a new BatchTable that provides all the batches of the input BatchTable
+// transformed with the BatchTransformFunc. It works exactly like Transform.
+func TransformBatches(source BatchTable, transform BatchTransformFunc, opts ...TransformOption) BatchTable {
+	return TypedTransform(source, transform, opts...)
+}
+
+// Batched returns a BatchTable that sends the Rows of a Table in batches of size Rows. A batch is
+// sent once it's full, or once the Table is done, so the last batch may be smaller.
+func Batched(table Table, size int, opts ...TransformOption) BatchTable {
+	size = max(size, 1)
+	return TypedTransform(table, func(in <-chan Row, out chan<- []Row) error {
+		batch := make([]Row, 0, size)
+		for row := range in {
+			if batch = append(batch, row); len(batch) == size {
+				out <- batch
+				batch = make([]Row, 0, size)
+			}
+		}
+		if len(batch) > 0 {
+			out <- batch
+		}
+		return nil
+	}, opts...)
+}
+
+// Unbatched returns a Table that sends the Rows of every batch of a BatchTable one at a time, so
+// that it can be used with transforms and sinks that work on Tables.
+func Unbatched(table BatchTable, opts ...TransformOption) Table {
+	return TypedTransform(table, func(in <-chan []Row, out chan<- Row) error {
+		for batch := range in {
+			for _, row := range batch {
+				out <- row
+			}
+		}
+		return nil
+	}, opts...)
+}
@@ -0,0 +1,78 @@
+package optimus
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func numberedRows(n int) []Row {
+	rows := []Row{}
+	for i := 0; i < n; i++ {
+		rows = append(rows, Row{"i": i})
+	}
+	return rows
+}
+
+var batchedTests = []struct {
+	rows  int
+	size  int
+	sizes []int
+}{
+	{rows: 0, size: 3, sizes: nil},
+	{rows: 2, size: 3, sizes: []int{2}},
+	{rows: 6, size: 3, sizes: []int{3, 3}},
+	{rows: 7, size: 3, sizes: []int{3, 3, 1}},
+	{rows: 2, size: 0, sizes: []int{1, 1}},
+}
+
+func TestBatched(t *testing.T) {
+	for _, batchedTest := range batchedTests {
+		desc := fmt.Sprintf("%d rows in batches of %d", batchedTest.rows, batchedTest.size)
+		table := Batched(newSliceTable(numberedRows(batchedTest.rows)...), batchedTest.size)
+		var sizes []int
+		rows := []Row{}
+		for batch := range table.Rows() {
+			sizes = append(sizes, len(batch))
+			rows = append(rows, batch...)
+		}
+		assert.Nil(t, table.Err(), desc)
+		assert.Equal(t, batchedTest.sizes, sizes, desc)
+		assert.Equal(t, numberedRows(batchedTest.rows), rows, desc)
+	}
+}
+
+func TestUnbatched(t *testing.T) {
+	batches := Batched(newSliceTable(numberedRows(7)...), 3)
+	doubled := TransformBatches(batches, func(in <-chan []Row, out chan<- []Row) error {
+		for batch := range in {
+			for _, row := range batch {
+				row["i"] = row["i"].(int) * 2
+			}
+			out <- batch
+		}
+		return nil
+	})
+	table := Unbatched(doubled, BufferSize(2))
+	rows := []Row{}
+	for row := range table.Rows() {
+		rows = append(rows, row)
+	}
+	assert.Nil(t, table.Err())
+	assert.Equal(t, []Row{{"i": 0}, {"i": 2}, {"i": 4}, {"i": 6}, {"i": 8}, {"i": 10}, {"i": 12}}, rows)
+}
+
+func TestBatchErrors(t *testing.T) {
+	table := Unbatched(TransformBatches(Batched(newSliceTable(numberedRows(5)...), 2),
+		func(in <-chan []Row, out chan<- []Row) error {
+			for range in {
+				return fmt.Errorf("batch error")
+			}
+			return nil
+		}))
+	for range table.Rows() {
+		t.Fatal("expected no rows")
+	}
+	assert.EqualError(t, table.Err(), "batch error")
+}
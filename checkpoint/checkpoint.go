@@ -0,0 +1,201 @@
+/*
+Package checkpoint lets long pipelines resume where they left off after they fail, instead of
+starting over.
+
+Resumable sources, like csv.NewResumable, json.NewResumable and mongo.NewResumable, add the
+position of each Row to its PositionField. A Sink made with NewSink commits Rows in batches, and
+after each batch is committed it saves the position of the batch's last Row to a Store. When the
+pipeline is restarted, the position is loaded from the Store and given to the source, which
+resumes after the last committed Row:
+
+	store := checkpoint.NewFileStore("export.checkpoint")
+	position, err := store.Load()
+	if err != nil {
+		return err
+	}
+	table := csvSource.NewResumable(f, position)
+	return transformer.New(table).Map(enrich).Sink(checkpoint.NewSink(checkpoint.Config{
+		Store: store,
+		Size:  1000,
+	}, writeToDatabase))
+
+Transforms between the source and the Sink have to keep the PositionField of the Rows they send,
+and must not reorder them, e.g. with Concurrently or Sort. Rows that are committed but whose
+position isn't saved before a failure are committed again when the pipeline resumes, so commits
+should be idempotent.
+*/
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sinks/batch"
+)
+
+// PositionField is the field that resumable sources put the position of each Row in. Positions
+// are opaque strings that only the source that created them understands.
+const PositionField = "_checkpoint_position"
+
+// Position returns the position of a Row from a resumable source.
+func Position(row optimus.Row) (string, bool) {
+	position, ok := row[PositionField].(string)
+	return position, ok
+}
+
+// Store saves the position of the last committed Row of a pipeline.
+type Store interface {
+	// Load returns the last position that was saved, or "" if there isn't one.
+	Load() (string, error)
+	// Save saves a position.
+	Save(position string) error
+	// Clear removes the saved position, so that the pipeline starts from the beginning.
+	Clear() error
+}
+
+// FileStore is a Store that saves the position in a file.
+type FileStore struct {
+	path string
+}
+
+// NewFileStore returns a Store that saves the position in the file at path. The file is replaced
+// atomically, so it always has a complete position, even if the process dies while saving.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load returns the position in the file, or "" if the file doesn't exist.
+func (s *FileStore) Load() (string, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	return string(data), err
+}
+
+// Save writes the position to a temporary file, and then renames it to the file.
+func (s *FileStore) Save(position string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(position); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// Clear removes the file.
+func (s *FileStore) Clear() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// MemoryStore is a Store that keeps the position in memory, e.g. for tests.
+type MemoryStore struct {
+	m        sync.Mutex
+	position string
+}
+
+// Load returns the saved position.
+func (s *MemoryStore) Load() (string, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.position, nil
+}
+
+// Save saves the position.
+func (s *MemoryStore) Save(position string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.position = position
+	return nil
+}
+
+// Clear removes the saved position.
+func (s *MemoryStore) Clear() error {
+	return s.Save("")
+}
+
+// Config configures a checkpointing Sink.
+type Config struct {
+	// Store is where the position of the last committed Row is saved.
+	Store Store
+	// Size is the largest number of Rows that are committed at once.
+	Size int
+	// Interval is the longest a Row waits for its batch to fill up before the batch is committed.
+	// Zero means batches are only committed when they're full or the Table is done.
+	Interval time.Duration
+	// Attempts is the number of times a batch is committed before giving up. Zero means once.
+	Attempts int
+	// Backoff is how long to wait before the first retry. It doubles after every retry.
+	Backoff time.Duration
+	// Clear clears the Store once every Row has been committed, so that the next run starts from
+	// the beginning. Otherwise the next run resumes after the last Row, e.g. to only process new
+	// documents.
+	Clear bool
+}
+
+// NewSink returns a Sink that groups the Rows of a Table into batches and calls commit with each
+// batch, in order, with a batch Sink. After a batch is committed, the position of its last Row that
+// has one is saved to the Store. The PositionField is removed from the Rows that are committed. If
+// commit or the Store returns an error, the batch is committed again according to the Config, and
+// if it keeps failing the Sink stops the Table and returns the error.
+func NewSink(config Config, commit func([]optimus.Row) error) optimus.Sink {
+	sink := batch.New(batch.Config{
+		Size:     config.Size,
+		Interval: config.Interval,
+		Attempts: config.Attempts,
+		Backoff:  config.Backoff,
+	}, func(rows []optimus.Row) error {
+		committed := make([]optimus.Row, len(rows))
+		last := ""
+		for i, row := range rows {
+			committed[i] = row
+			if position, ok := Position(row); ok {
+				last = position
+				committed[i] = withoutPosition(row)
+			}
+		}
+		if err := commit(committed); err != nil {
+			return err
+		}
+		if last != "" {
+			return config.Store.Save(last)
+		}
+		return nil
+	})
+	return func(source optimus.Table) error {
+		if err := sink(source); err != nil {
+			return err
+		}
+		if config.Clear {
+			return config.Store.Clear()
+		}
+		return nil
+	}
+}
+
+func withoutPosition(row optimus.Row) optimus.Row {
+	out := make(optimus.Row, len(row))
+	for k, v := range row {
+		if k != PositionField {
+			out[k] = v
+		}
+	}
+	return out
+}
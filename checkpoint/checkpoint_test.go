@@ -0,0 +1,109 @@
+package checkpoint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	errorSource "github.com/Clever/optimus/v4/sources/error"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(filepath.Join(dir, "checkpoint"))
+	position, err := store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "", position)
+
+	assert.Nil(t, store.Save("12"))
+	assert.Nil(t, store.Save("34"))
+	position, err = store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "34", position)
+
+	// Only the checkpoint file is left behind, with no temporary files.
+	files, err := os.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(files))
+
+	assert.Nil(t, store.Clear())
+	assert.Nil(t, store.Clear())
+	position, err = store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "", position)
+
+	assert.Error(t, NewFileStore(filepath.Join(dir, "missing", "checkpoint")).Save("12"))
+}
+
+var positioned = []optimus.Row{
+	{"id": 1, PositionField: "a"},
+	{"id": 2, PositionField: "b"},
+	{"id": 3},
+	{"id": 4, PositionField: "d"},
+	{"id": 5},
+}
+
+func TestSink(t *testing.T) {
+	store := &MemoryStore{}
+	saved := []string{}
+	batches := [][]optimus.Row{}
+	sink := NewSink(Config{Store: store, Size: 2}, func(rows []optimus.Row) error {
+		batches = append(batches, rows)
+		position, _ := store.Load()
+		saved = append(saved, position)
+		return nil
+	})
+	assert.Nil(t, sink(slice.New(positioned)))
+	assert.Equal(t, [][]optimus.Row{{{"id": 1}, {"id": 2}}, {{"id": 3}, {"id": 4}}, {{"id": 5}}}, batches)
+	// The position is saved after each batch is committed, and batches without positions don't
+	// change it.
+	assert.Equal(t, []string{"", "b", "d"}, saved)
+	position, _ := store.Load()
+	assert.Equal(t, "d", position)
+
+	sink = NewSink(Config{Store: store, Size: 2, Clear: true}, func(rows []optimus.Row) error { return nil })
+	assert.Nil(t, sink(slice.New(positioned)))
+	position, _ = store.Load()
+	assert.Equal(t, "", position)
+}
+
+func TestSinkErrors(t *testing.T) {
+	store := &MemoryStore{}
+	sink := NewSink(Config{Store: store, Size: 2}, func(rows []optimus.Row) error {
+		if rows[0]["id"] == 3 {
+			return fmt.Errorf("commit error")
+		}
+		return nil
+	})
+	assert.EqualError(t, sink(slice.New(positioned)),
+		"writing a batch of 2 rows failed after 1 attempts: commit error")
+	position, _ := store.Load()
+	assert.Equal(t, "b", position)
+
+	sink = NewSink(Config{Store: store, Size: 2}, func(rows []optimus.Row) error { return nil })
+	assert.EqualError(t, sink(errorSource.New(fmt.Errorf("source error"))), "source error")
+
+	sink = NewSink(Config{Store: NewFileStore(filepath.Join(t.TempDir(), "missing", "checkpoint")), Size: 2},
+		func(rows []optimus.Row) error { return nil })
+	assert.Error(t, sink(slice.New(positioned)))
+}
+
+func TestSinkRetries(t *testing.T) {
+	store := &MemoryStore{}
+	calls := 0
+	sink := NewSink(Config{Store: store, Size: 2, Attempts: 2}, func(rows []optimus.Row) error {
+		calls++
+		if calls == 2 {
+			return fmt.Errorf("temporary error")
+		}
+		return nil
+	})
+	assert.Nil(t, sink(slice.New(positioned)))
+	assert.Equal(t, 4, calls)
+	position, _ := store.Load()
+	assert.Equal(t, "d", position)
+}
@@ -0,0 +1,419 @@
+package optimus
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// structField is a field of a struct that's decoded from and encoded into a Row.
+type structField struct {
+	name      string
+	index     []int
+	omitEmpty bool
+	depth     int
+}
+
+// structInfo describes how a struct type is decoded and encoded.
+type structInfo struct {
+	fields []structField
+	// known is the set of Row keys that the struct decodes.
+	known map[string]bool
+}
+
+// structInfos caches the structInfo of each struct type, keyed by reflect.Type.
+var structInfos sync.Map
+
+// infoOf returns how a struct type is decoded and encoded. Each exported field is named by its
+// `optimus:"name"` tag, or by its Go name if it doesn't have one. Fields tagged `optimus:"-"` are
+// skipped. The fields of embedded structs without a tag are treated as if they were in the outer
+// struct, unless the outer struct, or a less deeply embedded struct, has a field with the same name.
+func infoOf(t reflect.Type) *structInfo {
+	if info, ok := structInfos.Load(t); ok {
+		return info.(*structInfo)
+	}
+	info := &structInfo{known: map[string]bool{}}
+	all := []structField{}
+	collectFields(t, nil, 0, &all)
+	// Shallower fields hide deeper ones with the same name.
+	sort.SliceStable(all, func(i, j int) bool { return all[i].depth < all[j].depth })
+	for _, f := range all {
+		if !info.known[f.name] {
+			info.known[f.name] = true
+			info.fields = append(info.fields, f)
+		}
+	}
+	structInfos.Store(t, info)
+	return info
+}
+
+func collectFields(t reflect.Type, index []int, depth int, fields *[]structField) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("optimus")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		fieldIndex := append(append([]int{}, index...), i)
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+				// Unexported embedded pointers can't be allocated when decoding.
+				if !f.IsExported() {
+					continue
+				}
+			}
+			if ft.Kind() == reflect.Struct {
+				collectFields(ft, fieldIndex, depth+1, fields)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		*fields = append(*fields, structField{
+			name:      name,
+			index:     fieldIndex,
+			omitEmpty: options == "omitempty",
+			depth:     depth,
+		})
+	}
+}
+
+// DecodeRow sets the exported fields of the struct that v points to from a Row. A field's key in
+// the Row is its `optimus:"name"` tag, or its name if it doesn't have one, and fields tagged
+// `optimus:"-"` are skipped. Fields of embedded structs are set as if they were fields of the
+// struct itself. Fields that aren't in the Row, or whose value is nil, are left as they are.
+//
+// Values are converted to their field's type where that can be done without losing information:
+// between numeric types, from strings to numbers and bools and back, from RFC 3339 strings to
+// time.Times, and element by element into slices, maps and pointers. Nested Rows and
+// map[string]interface{}s are decoded into struct fields.
+func DecodeRow(row Row, v interface{}) error {
+	return decodeRow(row, v, false)
+}
+
+// DecodeRowStrict is like DecodeRow, but returns an error if the Row has a field that the struct
+// doesn't decode, e.g. because of a typo. Fields tagged `optimus:"-"` don't decode any field.
+func DecodeRowStrict(row Row, v interface{}) error {
+	return decodeRow(row, v, true)
+}
+
+func decodeRow(row Row, v interface{}, strict bool) error {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Ptr || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("DecodeRow needs a non-nil pointer to a struct, got %T", v)
+	}
+	return decodeStruct(row, ptr.Elem(), strict)
+}
+
+func decodeStruct(row map[string]interface{}, s reflect.Value, strict bool) error {
+	info := infoOf(s.Type())
+	if strict {
+		unknown := []string{}
+		for key := range row {
+			if !info.known[key] {
+				unknown = append(unknown, key)
+			}
+		}
+		if len(unknown) > 0 {
+			sort.Strings(unknown)
+			return fmt.Errorf("cannot decode '%s' into %s, it has no such field", unknown[0], s.Type().Name())
+		}
+	}
+	for _, f := range info.fields {
+		val, ok := row[f.name]
+		if !ok || val == nil {
+			continue
+		}
+		field := fieldByIndex(s, f.index, true)
+		if err := decodeValue(field, val, strict); err != nil {
+			name := s.Type().FieldByIndex(f.index).Name
+			if err != errDecode {
+				// A nested struct couldn't be decoded, and its error says which of its fields failed.
+				return fmt.Errorf("cannot decode '%s' into %s.%s: %w", f.name, s.Type().Name(), name, err)
+			}
+			return fmt.Errorf("cannot decode '%s' into %s.%s of type %s, had value: %#v",
+				f.name, s.Type().Name(), name, field.Type(), val)
+		}
+	}
+	return nil
+}
+
+// fieldByIndex is like reflect.Value.FieldByIndex, but if it comes across a nil embedded pointer,
+// it either allocates it or returns an invalid Value.
+func fieldByIndex(v reflect.Value, index []int, allocate bool) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !allocate {
+					return reflect.Value{}
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	errDecode = errors.New("cannot decode")
+)
+
+// decodeValue sets dst to val, converting it if it has to. It returns errDecode if val can't be
+// converted, which the caller describes, or the error of a nested struct that can't be decoded.
+func decodeValue(dst reflect.Value, val interface{}, strict bool) error {
+	if val == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	rv := reflect.ValueOf(val)
+	if rv.Type().AssignableTo(dst.Type()) {
+		dst.Set(rv)
+		return nil
+	}
+	switch dst.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(dst.Type().Elem())
+		if err := decodeValue(elem.Elem(), val, strict); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := toInt64(rv); ok && !dst.OverflowInt(n) {
+			dst.SetInt(n)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := toInt64(rv); ok && n >= 0 && !dst.OverflowUint(uint64(n)) {
+			dst.SetUint(uint64(n))
+			return nil
+		}
+		if rv.CanUint() && !dst.OverflowUint(rv.Uint()) {
+			dst.SetUint(rv.Uint())
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, ok := toFloat64(rv); ok && !dst.OverflowFloat(f) {
+			dst.SetFloat(f)
+			return nil
+		}
+	case reflect.Bool:
+		if rv.Kind() == reflect.Bool {
+			dst.SetBool(rv.Bool())
+			return nil
+		}
+		if rv.Kind() == reflect.String {
+			if b, err := strconv.ParseBool(rv.String()); err == nil {
+				dst.SetBool(b)
+				return nil
+			}
+		}
+	case reflect.String:
+		if s, ok := toString(rv); ok {
+			dst.SetString(s)
+			return nil
+		}
+	case reflect.Struct:
+		if dst.Type() == timeType && rv.Kind() == reflect.String {
+			t, err := time.Parse(time.RFC3339, rv.String())
+			if err != nil {
+				return errDecode
+			}
+			dst.Set(reflect.ValueOf(t))
+			return nil
+		}
+		if m, ok := toStringMap(rv); ok {
+			return decodeStruct(m, dst, strict)
+		}
+	case reflect.Slice:
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			out := reflect.MakeSlice(dst.Type(), rv.Len(), rv.Len())
+			for i := 0; i < rv.Len(); i++ {
+				if err := decodeValue(out.Index(i), rv.Index(i).Interface(), strict); err != nil {
+					return err
+				}
+			}
+			dst.Set(out)
+			return nil
+		}
+	case reflect.Map:
+		if rv.Kind() == reflect.Map && rv.Type().Key().ConvertibleTo(dst.Type().Key()) {
+			out := reflect.MakeMapWithSize(dst.Type(), rv.Len())
+			iter := rv.MapRange()
+			for iter.Next() {
+				elem := reflect.New(dst.Type().Elem()).Elem()
+				if err := decodeValue(elem, iter.Value().Interface(), strict); err != nil {
+					return err
+				}
+				out.SetMapIndex(iter.Key().Convert(dst.Type().Key()), elem)
+			}
+			dst.Set(out)
+			return nil
+		}
+	}
+	// Named types with the same underlying kind, like a `type Grade string`.
+	if rv.Kind() == dst.Kind() && rv.Type().ConvertibleTo(dst.Type()) {
+		dst.Set(rv.Convert(dst.Type()))
+		return nil
+	}
+	return errDecode
+}
+
+// toInt64 converts integers, whole floats and strings of integers to an int64.
+func toInt64(rv reflect.Value) (int64, bool) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n := rv.Uint(); n <= math.MaxInt64 {
+			return int64(n), true
+		}
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64 {
+			return int64(f), true
+		}
+	case reflect.String:
+		n, err := strconv.ParseInt(strings.TrimSpace(rv.String()), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// toFloat64 converts numbers and strings of numbers to a float64.
+func toFloat64(rv reflect.Value) (float64, bool) {
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	case reflect.String:
+		f, err := strconv.ParseFloat(strings.TrimSpace(rv.String()), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toString converts strings, numbers, bools and []bytes to a string.
+func toString(rv reflect.Value) (string, bool) {
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(rv.Uint(), 10), true
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32), true
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), true
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), true
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return string(rv.Bytes()), true
+		}
+	}
+	return "", false
+}
+
+// toStringMap converts Rows and other maps with string keys to a map[string]interface{}.
+func toStringMap(rv reflect.Value) (map[string]interface{}, bool) {
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+	m := make(map[string]interface{}, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		m[iter.Key().String()] = iter.Value().Interface()
+	}
+	return m, true
+}
+
+// EncodeRow returns a Row with the exported fields of a struct, or of the struct that v points to.
+// Fields are named the same way as in DecodeRow, and fields of embedded structs are added as if
+// they were fields of the struct itself. Fields tagged `optimus:"name,omitempty"` are left out if
+// they're empty: the zero value, or an empty slice, map or string. Other values are added as they
+// are.
+func EncodeRow(v interface{}) (Row, error) {
+	s := reflect.ValueOf(v)
+	if s.Kind() == reflect.Ptr && !s.IsNil() {
+		s = s.Elem()
+	}
+	if s.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("EncodeRow needs a struct, got %T", v)
+	}
+	row := Row{}
+	for _, f := range infoOf(s.Type()).fields {
+		field := fieldByIndex(s, f.index, false)
+		if !field.IsValid() || (f.omitEmpty && isEmpty(field)) {
+			continue
+		}
+		row[f.name] = field.Interface()
+	}
+	return row, nil
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// Decode returns a TypedTable that decodes every Row of a Table into a struct of type T with
+// DecodeRow. Decoding errors are returned by the TypedTable's Err.
+func Decode[T any](table Table) TypedTable[T] {
+	return decode[T](table, DecodeRow)
+}
+
+// DecodeStrict is like Decode, but uses DecodeRowStrict.
+func DecodeStrict[T any](table Table) TypedTable[T] {
+	return decode[T](table, DecodeRowStrict)
+}
+
+func decode[T any](table Table, decodeRow func(Row, interface{}) error) TypedTable[T] {
+	return TypedTransform(table, func(in <-chan Row, out chan<- T) error {
+		for row := range in {
+			var v T
+			if err := decodeRow(row, &v); err != nil {
+				return err
+			}
+			out <- v
+		}
+		return nil
+	})
+}
+
+// Encode returns a Table that encodes every struct of a TypedTable into a Row with EncodeRow, so
+// that it can be used with transforms and sinks that work on Tables.
+func Encode[T any](table TypedTable[T]) Table {
+	return TypedTransform(table, func(in <-chan T, out chan<- Row) error {
+		for v := range in {
+			row, err := EncodeRow(v)
+			if err != nil {
+				return err
+			}
+			out <- row
+		}
+		return nil
+	})
+}
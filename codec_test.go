@@ -0,0 +1,241 @@
+package optimus
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sliceTable is a minimal Table of Rows, since the slice source can't be imported here.
+type sliceTable struct {
+	rows chan Row
+	stop chan struct{}
+	once sync.Once
+}
+
+func newSliceTable(rows ...Row) *sliceTable {
+	t := &sliceTable{rows: make(chan Row), stop: make(chan struct{})}
+	go func() {
+		defer close(t.rows)
+		for _, row := range rows {
+			select {
+			case t.rows <- row:
+			case <-t.stop:
+				return
+			}
+		}
+	}()
+	return t
+}
+
+func (t *sliceTable) Rows() <-chan Row { return t.rows }
+func (t *sliceTable) Err() error       { return nil }
+func (t *sliceTable) Stop()            { t.once.Do(func() { close(t.stop) }) }
+
+type student struct {
+	ID     string `optimus:"id"`
+	Name   string `optimus:"name"`
+	Grade  int    `optimus:"grade"`
+	School string
+	Secret string `optimus:"-"`
+	note   string
+}
+
+func TestDecodeRow(t *testing.T) {
+	var s student
+	assert.Nil(t, DecodeRow(Row{"id": "1", "name": "Ada", "grade": 9, "School": "s1", "Secret": "x", "note": "y"}, &s))
+	assert.Equal(t, student{ID: "1", Name: "Ada", Grade: 9, School: "s1"}, s)
+
+	// Missing and nil fields are left as they are.
+	s = student{Name: "Grace"}
+	assert.Nil(t, DecodeRow(Row{"id": "2", "name": nil}, &s))
+	assert.Equal(t, student{ID: "2", Name: "Grace"}, s)
+
+	assert.EqualError(t, DecodeRow(Row{"grade": "nine"}, &s),
+		`cannot decode 'grade' into student.Grade of type int, had value: "nine"`)
+	assert.EqualError(t, DecodeRow(Row{}, s), "DecodeRow needs a non-nil pointer to a struct, got optimus.student")
+}
+
+func TestEncodeRow(t *testing.T) {
+	s := student{ID: "1", Name: "Ada", Grade: 9, Secret: "x", note: "y"}
+	expected := Row{"id": "1", "name": "Ada", "grade": 9, "School": ""}
+	row, err := EncodeRow(s)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, row)
+	row, err = EncodeRow(&s)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, row)
+
+	_, err = EncodeRow(Row{})
+	assert.EqualError(t, err, "EncodeRow needs a struct, got optimus.Row")
+}
+
+func TestTypedTransform(t *testing.T) {
+	students := Decode[student](newSliceTable(
+		Row{"id": "1", "name": "ada", "grade": 9},
+		Row{"id": "2", "name": "grace", "grade": 10},
+	))
+	promoted := TypedTransform(students, func(in <-chan student, out chan<- student) error {
+		for s := range in {
+			s.Name = strings.ToUpper(s.Name[:1]) + s.Name[1:]
+			s.Grade++
+			out <- s
+		}
+		return nil
+	})
+	table := Encode(promoted)
+	rows := []Row{}
+	for row := range table.Rows() {
+		rows = append(rows, row)
+	}
+	assert.Nil(t, table.Err())
+	assert.Equal(t, []Row{
+		{"id": "1", "name": "Ada", "grade": 10, "School": ""},
+		{"id": "2", "name": "Grace", "grade": 11, "School": ""},
+	}, rows)
+}
+
+func TestTypedTransformErrors(t *testing.T) {
+	source := newSliceTable(Row{"grade": "nine"}, Row{"grade": 10})
+	table := Decode[student](source)
+	for range table.Rows() {
+		t.Fatal("expected no rows")
+	}
+	assert.EqualError(t, table.Err(), `cannot decode 'grade' into student.Grade of type int, had value: "nine"`)
+
+	grades := TypedTransform(Decode[student](newSliceTable(Row{"grade": 9})),
+		func(in <-chan student, out chan<- int) error {
+			for range in {
+				return fmt.Errorf("grade error")
+			}
+			return nil
+		})
+	for range grades.Rows() {
+		t.Fatal("expected no grades")
+	}
+	assert.EqualError(t, grades.Err(), "grade error")
+}
+
+type Timestamps struct {
+	Created time.Time  `optimus:"created_at"`
+	Updated *time.Time `optimus:"updated_at,omitempty"`
+}
+
+type Address struct {
+	City string `optimus:"city"`
+	Zip  string `optimus:"zip,omitempty"`
+}
+
+type Level string
+
+type teacher struct {
+	Timestamps
+	*Address
+	Name     string            `optimus:"name"`
+	Level    Level             `optimus:"level"`
+	Years    uint8             `optimus:"years,omitempty"`
+	Rating   float32           `optimus:"rating"`
+	Active   bool              `optimus:"active"`
+	Classes  []int             `optimus:"classes,omitempty"`
+	Extra    map[string]string `optimus:"extra,omitempty"`
+	Home     Address           `optimus:"home"`
+	Nickname *string           `optimus:"nickname"`
+	City     string            `optimus:"hometown"`
+}
+
+func TestDecodeRowConversions(t *testing.T) {
+	var tc teacher
+	assert.Nil(t, DecodeRow(Row{
+		"created_at": "2024-01-02T03:04:05Z",
+		"city":       "Oakland",
+		"name":       "Ms. Frizzle",
+		"level":      "senior",
+		"years":      float64(12),
+		"rating":     "4.5",
+		"active":     "true",
+		"classes":    []interface{}{float64(1), "2", 3},
+		"extra":      map[string]interface{}{"room": 12},
+		"home":       Row{"city": "Walkerville", "zip": 94110},
+		"nickname":   "Frizz",
+		"hometown":   "Springfield",
+	}, &tc))
+	nickname := "Frizz"
+	assert.Equal(t, teacher{
+		Timestamps: Timestamps{Created: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)},
+		Address:    &Address{City: "Oakland"},
+		Name:       "Ms. Frizzle",
+		Level:      "senior",
+		Years:      12,
+		Rating:     4.5,
+		Active:     true,
+		Classes:    []int{1, 2, 3},
+		Extra:      map[string]string{"room": "12"},
+		Home:       Address{City: "Walkerville", Zip: "94110"},
+		Nickname:   &nickname,
+		City:       "Springfield",
+	}, tc)
+
+	for _, row := range []Row{
+		{"years": 256},
+		{"years": -1},
+		{"years": 1.5},
+		{"classes": []interface{}{"one"}},
+		{"created_at": "yesterday"},
+		{"home": "Walkerville"},
+	} {
+		assert.Error(t, DecodeRow(row, &tc), "%#v", row)
+	}
+
+	// The errors of nested structs are wrapped with the path to the field that failed.
+	err := DecodeRow(Row{"home": Row{"zip": []int{1}}}, &tc)
+	assert.EqualError(t, err, "cannot decode 'home' into teacher.Home: "+
+		"cannot decode 'zip' into Address.Zip of type string, had value: []int{1}")
+	assert.EqualError(t, errors.Unwrap(err),
+		"cannot decode 'zip' into Address.Zip of type string, had value: []int{1}")
+}
+
+func TestDecodeRowStrict(t *testing.T) {
+	var s student
+	assert.EqualError(t, DecodeRowStrict(Row{"id": "1", "nmae": "Ada", "zzz": 1}, &s),
+		"cannot decode 'nmae' into student, it has no such field")
+	// Skipped fields don't decode anything, so strict decoding doesn't accept them either.
+	assert.EqualError(t, DecodeRowStrict(Row{"id": "1", "Secret": "x"}, &s),
+		"cannot decode 'Secret' into student, it has no such field")
+	// Lenient decoding ignores unknown fields.
+	assert.Nil(t, DecodeRow(Row{"id": "1", "nmae": "Ada"}, &s))
+
+	var tc teacher
+	assert.EqualError(t, DecodeRowStrict(Row{"home": Row{"city": "Walkerville", "country": "US"}}, &tc),
+		"cannot decode 'home' into teacher.Home: cannot decode 'country' into Address, it has no such field")
+	assert.Nil(t, DecodeRow(Row{"home": Row{"city": "Walkerville", "country": "US"}}, &tc))
+}
+
+func TestEncodeRowOmitEmptyAndEmbedded(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	row, err := EncodeRow(teacher{Timestamps: Timestamps{Created: created}, Name: "Ms. Frizzle"})
+	assert.Nil(t, err)
+	// The nil embedded *Address's fields are left out, and so are the empty omitempty fields.
+	assert.Equal(t, Row{
+		"created_at": created,
+		"name":       "Ms. Frizzle",
+		"level":      Level(""),
+		"rating":     float32(0),
+		"active":     false,
+		"home":       Address{},
+		"nickname":   (*string)(nil),
+		"hometown":   "",
+	}, row)
+
+	row, err = EncodeRow(teacher{Address: &Address{City: "Oakland", Zip: "94607"}, Years: 3, Classes: []int{1}})
+	assert.Nil(t, err)
+	assert.Equal(t, "Oakland", row["city"])
+	assert.Equal(t, "94607", row["zip"])
+	assert.Equal(t, uint8(3), row["years"])
+	assert.Equal(t, []int{1}, row["classes"])
+	assert.NotContains(t, row, "updated_at")
+}
@@ -9,6 +9,11 @@ Several implementations of TransformFuncs are provided for common transformation
 
 Lastly, a set of Sink functions are provided that will "sink" a table into some output, such as a CSV.
 
+For compile-time safety, a TypedTable[T] is a Table of structs instead of Rows, and a
+TypedTransform applies a function of those structs to it. Decode and Encode convert between Tables
+and TypedTables using `optimus:"name"` struct tags, so that TypedTables work with every source,
+transform and sink.
+
 # Example
 
 Here's an example program that performs a set of field and value mappings on a CSV file:
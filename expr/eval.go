@@ -0,0 +1,281 @@
+package expr
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/transforms"
+)
+
+// node is a node in an expression's syntax tree.
+type node interface {
+	eval(row optimus.Row) (interface{}, error)
+	pos() Position
+}
+
+type literal struct {
+	at    Position
+	value interface{}
+}
+
+func (n *literal) pos() Position { return n.at }
+
+func (n *literal) eval(optimus.Row) (interface{}, error) {
+	return n.value, nil
+}
+
+// field is a reference to a field of the Row, which may be a dotted path into nested objects.
+// Missing fields are null.
+type field struct {
+	at   Position
+	path string
+}
+
+func (n *field) pos() Position { return n.at }
+
+func (n *field) eval(row optimus.Row) (interface{}, error) {
+	val, _ := transforms.GetPath(row, n.path)
+	return val, nil
+}
+
+// unary is either a negation or a boolean not.
+type unary struct {
+	at      Position
+	not     bool
+	operand node
+}
+
+func (n *unary) pos() Position { return n.at }
+
+func (n *unary) eval(row optimus.Row) (interface{}, error) {
+	val, err := n.operand.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	if n.not {
+		b, err := truthy(n.operand, val)
+		return !b, err
+	}
+	if val == nil {
+		return nil, nil
+	}
+	f, ok := toNumber(val)
+	if !ok {
+		return nil, errorf(n.at, "'-' needs a number, got %s", describe(val))
+	}
+	return -f, nil
+}
+
+// logical is an and or an or, which only evaluates its right side if it has to.
+type logical struct {
+	at          Position
+	and         bool
+	left, right node
+}
+
+func (n *logical) pos() Position { return n.at }
+
+func (n *logical) eval(row optimus.Row) (interface{}, error) {
+	for _, side := range []node{n.left, n.right} {
+		val, err := side.eval(row)
+		if err != nil {
+			return nil, err
+		}
+		b, err := truthy(side, val)
+		if err != nil {
+			return nil, err
+		}
+		if b != n.and {
+			return b, nil
+		}
+	}
+	return n.and, nil
+}
+
+type binary struct {
+	at          Position
+	op          string
+	left, right node
+}
+
+func (n *binary) pos() Position { return n.at }
+
+func (n *binary) eval(row optimus.Row) (interface{}, error) {
+	left, err := n.left.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(row)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	}
+	// Every other operator is null if either side is.
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	switch n.op {
+	case "<", "<=", ">", ">=":
+		cmp, ok := compare(left, right)
+		if !ok {
+			return nil, errorf(n.at, "can't compare %s and %s", describe(left), describe(right))
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		}
+		return cmp >= 0, nil
+	}
+	l, lok := toNumber(left)
+	r, rok := toNumber(right)
+	if !lok || !rok {
+		hint := ""
+		if _, ok := left.(string); ok {
+			hint = " (use concat to join strings, or number to convert them)"
+		} else if _, ok := right.(string); ok {
+			hint = " (use concat to join strings, or number to convert them)"
+		}
+		return nil, errorf(n.at, "'%s' needs numbers, got %s and %s%s", n.op, describe(left), describe(right), hint)
+	}
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	}
+	if r == 0 {
+		return nil, errorf(n.at, "division by zero")
+	}
+	if n.op == "/" {
+		return l / r, nil
+	}
+	return math.Mod(l, r), nil
+}
+
+type call struct {
+	at   Position
+	fn   *function
+	args []node
+}
+
+func (n *call) pos() Position { return n.at }
+
+func (n *call) eval(row optimus.Row) (interface{}, error) {
+	var val interface{}
+	var err error
+	if n.fn.lazy != nil {
+		val, err = n.fn.lazy(row, n.args)
+	} else {
+		args := make([]interface{}, len(n.args))
+		for i, arg := range n.args {
+			if args[i], err = arg.eval(row); err != nil {
+				return nil, err
+			}
+		}
+		val, err = n.fn.eval(args)
+	}
+	if err != nil {
+		var exprErr *Error
+		if errors.As(err, &exprErr) {
+			return nil, err
+		}
+		return nil, errorf(n.at, "%s: %s", n.fn.name, err)
+	}
+	return val, nil
+}
+
+// truthy returns the boolean value of a node's value. Null is false, and anything else that isn't
+// a boolean is an error.
+func truthy(n node, val interface{}) (bool, error) {
+	switch val := val.(type) {
+	case bool:
+		return val, nil
+	case nil:
+		return false, nil
+	}
+	return false, errorf(n.pos(), "expected a boolean, got %s", describe(val))
+}
+
+// toNumber converts any of Go's numeric types to a float64.
+func toNumber(val interface{}) (float64, bool) {
+	switch val := val.(type) {
+	case float64:
+		return val, true
+	case int:
+		return float64(val), true
+	}
+	v := reflect.ValueOf(val)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// equal reports whether two values are equal. Numbers of different types are equal if they have
+// the same value.
+func equal(left, right interface{}) bool {
+	if l, ok := toNumber(left); ok {
+		r, ok := toNumber(right)
+		return ok && l == r
+	}
+	if l, ok := left.(time.Time); ok {
+		r, ok := right.(time.Time)
+		return ok && l.Equal(r)
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+// compare orders two numbers, strings or times.
+func compare(left, right interface{}) (int, bool) {
+	if l, ok := toNumber(left); ok {
+		if r, ok := toNumber(right); ok {
+			return cmp.Compare(l, r), true
+		}
+		return 0, false
+	}
+	switch l := left.(type) {
+	case string:
+		if r, ok := right.(string); ok {
+			return cmp.Compare(l, r), true
+		}
+	case time.Time:
+		if r, ok := right.(time.Time); ok {
+			return l.Compare(r), true
+		}
+	}
+	return 0, false
+}
+
+// describe formats a value for an error message.
+func describe(val interface{}) string {
+	switch val := val.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("%q", val)
+	case time.Time:
+		return val.Format(time.RFC3339)
+	}
+	return fmt.Sprintf("%v", val)
+}
@@ -0,0 +1,140 @@
+/*
+Package expr is a small expression language for computing fields from Rows and filtering Rows,
+so that transforms can be written as strings, e.g. in a config file or on the command line.
+
+	concat(first, " ", last)
+	number(age) >= 18 and not contains(lower(email), "example.com")
+	format_date(parse_date(created_at), "2006-01-02")
+
+Expressions are made of:
+
+Field references, like name or address.city, which is a path into nested objects like
+transforms.GetPath. Missing fields are null. Fields whose names aren't valid in expressions can be
+referenced with field("first name").
+
+Literals: numbers like 3 or 2.5e3, strings in single or double quotes with Go's backslash escapes,
+true, false and null.
+
+Arithmetic with +, -, *, / and %. Operands must be numbers; strings can be converted with number.
+The result is null if either operand is.
+
+Comparisons with ==, !=, <, <=, > and >=. Numbers, strings and dates can be ordered, and ordering
+anything with null is null. Numbers are equal if they have the same value, whatever their type.
+
+Boolean logic with and (or &&), or (or ||) and not (or !). Null is false, and anything else that
+isn't a boolean is an error. And and or only evaluate their right side if they need to.
+
+Function calls:
+
+	concat(a, ...)                join values into a string, skipping nulls
+	lower(s), upper(s), trim(s)   change a string's case or trim its whitespace
+	len(v)                        the length of a string, array or object
+	substr(s, start[, length])    part of a string, starting at the 0-based start
+	contains(s, sub)              whether s contains sub
+	starts_with(s, prefix)        whether s starts with prefix
+	ends_with(s, suffix)          whether s ends with suffix
+	replace(s, old, new)          replace every old in s with new
+	number(v)                     convert a string to a number; empty strings are null
+	string(v)                     convert a value to a string
+	abs(n), round(n)              a number's absolute value or nearest whole number
+	floor(n), ceil(n)             round a number down or up
+	parse_date(s[, layout])       parse a date with a Go time layout, RFC 3339 by default
+	format_date(d, layout)        format a date with a Go time layout
+	coalesce(a, ...)              the first value that isn't null
+	if(cond, a, b)                a if cond is true, otherwise b
+	field(name)                   the field with exactly this name
+
+Most functions return null when their first argument is null.
+
+Errors, both from compiling and evaluating an expression, are *Errors with the line and column of
+the problem, e.g. "1:1: unknown function 'lowr'".
+*/
+package expr
+
+import (
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/transforms"
+)
+
+// Expr is a compiled expression. It's safe to use concurrently.
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile parses an expression.
+func Compile(src string) (*Expr, error) {
+	root, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// MustCompile is like Compile, but panics if the expression can't be parsed.
+func MustCompile(src string) *Expr {
+	e, err := Compile(src)
+	if err != nil {
+		panic(`expr: Compile(` + src + `): ` + err.Error())
+	}
+	return e
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Eval evaluates the expression against a Row. Fields are returned as they are in the Row, but
+// numbers that the expression computes are float64s, and dates are time.Times.
+func (e *Expr) Eval(row optimus.Row) (interface{}, error) {
+	return e.root.eval(row)
+}
+
+// Test evaluates the expression against a Row as a condition. It's an error if the expression
+// isn't a boolean or null, which is false.
+func (e *Expr) Test(row optimus.Row) (bool, error) {
+	val, err := e.root.eval(row)
+	if err != nil {
+		return false, err
+	}
+	return truthy(e.root, val)
+}
+
+// Assign returns a function for transforms.Map that sets a field of each Row to the value of the
+// expression. The field may be a dotted path into nested objects, like transforms.SetPath. The
+// Rows aren't modified; copies are returned.
+func (e *Expr) Assign(field string) func(optimus.Row) (optimus.Row, error) {
+	return func(row optimus.Row) (optimus.Row, error) {
+		val, err := e.Eval(row)
+		if err != nil {
+			return nil, err
+		}
+		out := optimus.Row{}
+		for k, v := range row {
+			out[k] = v
+		}
+		if err := transforms.SetPath(out, field, val); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+}
+
+// Derive returns a TransformFunc that sets a field of every Row to the value of an expression.
+func Derive(field, src string) (optimus.TransformFunc, error) {
+	e, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return transforms.Map(e.Assign(field)), nil
+}
+
+// Where returns a TransformFunc that only keeps the Rows for which an expression is true.
+func Where(src string) (optimus.TransformFunc, error) {
+	e, err := Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return transforms.Select(e.Test), nil
+}
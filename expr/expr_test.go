@@ -0,0 +1,149 @@
+package expr
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+var person = optimus.Row{
+	"first":   "Ada",
+	"last":    "Lovelace",
+	"age":     "36",
+	"born":    "1815-12-10",
+	"score":   7,
+	"ratio":   float32(0.5),
+	"email":   " ADA@Example.com ",
+	"tags":    []string{"math", "poetry"},
+	"address": map[string]interface{}{"city": "London"},
+	"the end": "yes",
+	"empty":   "",
+}
+
+var evalTests = []struct {
+	src      string
+	expected interface{}
+}{
+	{`concat(first, " ", last)`, "Ada Lovelace"},
+	{`concat(first, missing, "!")`, "Ada!"},
+	{`concat("score: ", score, ", ratio: ", ratio)`, "score: 7, ratio: 0.5"},
+	{`lower(trim(email))`, "ada@example.com"},
+	{`upper(address.city)`, "LONDON"},
+	{`len(last) + len(tags) + len(address) + len(missing)`, float64(11)},
+	{`substr(last, 4)`, "lace"},
+	{`substr(last, 0, 4)`, "Love"},
+	{`substr(last, 6, 100)`, "ce"},
+	{`substr(last, 100)`, ""},
+	{`replace(last, "e", "3")`, "Lov3lac3"},
+	{`contains(email, "Example") and starts_with(first, "A") and ends_with(first, "a")`, true},
+	{`1 + 2 * 3 - 4 / 2`, float64(5)},
+	{`(1 + 2) * 3`, float64(9)},
+	{`-score % 4`, float64(-3)},
+	{`score * ratio`, float64(3.5)},
+	{`number(age) + 1`, float64(37)},
+	{`number(empty)`, nil},
+	{`string(score / 2)`, "3.5"},
+	{`abs(-2) + round(2.5) + floor(2.5) + ceil(2.1)`, float64(10)},
+	{`missing + 1`, nil},
+	{`missing > 1`, nil},
+	{`missing == null`, true},
+	{`score == 7`, true},
+	{`score != 7.5`, true},
+	{`"b" > "a" && 2 >= 2 && !(1 > 2)`, true},
+	{`first == "ada" or missing`, false},
+	{`false and missing.field > 1`, false},
+	{`coalesce(missing, address.zip, "none")`, "none"},
+	{`if(number(age) >= 18, "adult", "minor")`, "adult"},
+	{`if(missing, 1 / 0, "short-circuited")`, "short-circuited"},
+	{`field("the end")`, "yes"},
+	{`parse_date(born, "2006-01-02")`, time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC)},
+	{`format_date(parse_date(born, "2006-01-02"), "Jan 2, 2006")`, "Dec 10, 1815"},
+	{`parse_date(born, "2006-01-02") < parse_date("1900-01-01T00:00:00Z")`, true},
+	{"first == 'Ada'\n\tand last == \"Lovelace\"", true},
+	{`"tab\there" == 'tab	here'`, true},
+}
+
+func TestEval(t *testing.T) {
+	for _, evalTest := range evalTests {
+		e, err := Compile(evalTest.src)
+		if !assert.Nil(t, err, evalTest.src) {
+			continue
+		}
+		val, err := e.Eval(person)
+		assert.Nil(t, err, evalTest.src)
+		assert.Equal(t, evalTest.expected, val, evalTest.src)
+	}
+}
+
+var evalErrorTests = []struct {
+	src string
+	err string
+}{
+	{`first + 1`, `1:7: '+' needs numbers, got "Ada" and 1 (use concat to join strings, or number to convert them)`},
+	{`score / 0`, "1:7: division by zero"},
+	{`-first`, `1:1: '-' needs a number, got "Ada"`},
+	{`first < 1`, `1:7: can't compare "Ada" and 1`},
+	{`score and true`, "1:1: expected a boolean, got 7"},
+	{`lower(score)`, "1:1: lower: expected a string, got 7"},
+	{`substr(first, 1.5)`, "1:1: substr: expected a whole number, got 1.5"},
+	{`number(first)`, `1:1: number: can't convert "Ada" to a number`},
+	{`parse_date(born)`, `1:1: parse_date: can't parse "1815-12-10" with layout "2006-01-02T15:04:05Z07:00"`},
+	{`format_date(born, "2006")`, `1:1: format_date: expected a date, got "1815-12-10" (use parse_date to convert strings)`},
+	{`upper(lower(1 / 0))`, "1:15: division by zero"},
+}
+
+func TestEvalErrors(t *testing.T) {
+	for _, evalErrorTest := range evalErrorTests {
+		e := MustCompile(evalErrorTest.src)
+		_, err := e.Eval(person)
+		assert.EqualError(t, err, evalErrorTest.err, evalErrorTest.src)
+		assert.IsType(t, &Error{}, err, evalErrorTest.src)
+	}
+}
+
+func TestDerive(t *testing.T) {
+	derive, err := Derive("name.full", `concat(first, " ", last)`)
+	assert.Nil(t, err)
+	input := []optimus.Row{{"first": "Ada", "last": "Lovelace"}, {"first": "Grace"}}
+	table := optimus.Transform(slice.New(input), derive)
+	assert.Equal(t, []optimus.Row{
+		{"first": "Ada", "last": "Lovelace", "name": map[string]interface{}{"full": "Ada Lovelace"}},
+		{"first": "Grace", "name": map[string]interface{}{"full": "Grace "}},
+	}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+	// The input Rows aren't modified.
+	assert.Equal(t, optimus.Row{"first": "Grace"}, input[1])
+
+	_, err = Derive("name", `concat(first`)
+	assert.EqualError(t, err, "1:13: expected ',' or ')' to close '(' at 1:7, found end of expression")
+
+	derive, _ = Derive("name", `upper(age)`)
+	table = optimus.Transform(slice.New([]optimus.Row{{"age": 3}}), derive)
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "1:1: upper: expected a string, got 3")
+}
+
+func TestWhere(t *testing.T) {
+	where, err := Where(`number(grade) >= 10 and school != "s2"`)
+	assert.Nil(t, err)
+	table := optimus.Transform(slice.New([]optimus.Row{
+		{"grade": "9", "school": "s1"},
+		{"grade": "10", "school": "s1"},
+		{"grade": "11", "school": "s2"},
+		{"grade": "", "school": "s1"},
+	}), where)
+	assert.Equal(t, []optimus.Row{{"grade": "10", "school": "s1"}}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+
+	_, err = Where(`grade >> 10`)
+	assert.EqualError(t, err, "1:8: expected an expression, found '>'")
+
+	where, _ = Where(`grade`)
+	table = optimus.Transform(slice.New([]optimus.Row{{"grade": "9"}}), where)
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), `1:1: expected a boolean, got "9"`)
+}
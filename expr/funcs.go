@@ -0,0 +1,347 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// function is a function that can be called in an expression.
+type function struct {
+	name string
+	// min and max are the number of arguments it takes. A max of -1 means any number.
+	min, max int
+	// eval is called with the values of the arguments.
+	eval func(args []interface{}) (interface{}, error)
+	// lazy, if it's set, is called instead of eval with the unevaluated arguments, so that it only
+	// evaluates the ones it needs.
+	lazy func(row optimus.Row, args []node) (interface{}, error)
+}
+
+// checkArgs returns an error message if the function can't be called with n arguments.
+func (f *function) checkArgs(n int) string {
+	if n >= f.min && (f.max < 0 || n <= f.max) {
+		return ""
+	}
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 argument"
+		}
+		return fmt.Sprintf("%d arguments", n)
+	}
+	switch {
+	case f.max < 0:
+		return fmt.Sprintf("%s takes at least %s, got %d", f.name, plural(f.min), n)
+	case f.min == f.max:
+		return fmt.Sprintf("%s takes %s, got %d", f.name, plural(f.min), n)
+	case f.max == f.min+1:
+		return fmt.Sprintf("%s takes %d or %s, got %d", f.name, f.min, plural(f.max), n)
+	}
+	return fmt.Sprintf("%s takes %d to %s, got %d", f.name, f.min, plural(f.max), n)
+}
+
+var functions = map[string]*function{}
+
+func init() {
+	for _, f := range []*function{
+		{name: "concat", min: 1, max: -1, eval: concat},
+		{name: "lower", min: 1, max: 1, eval: stringFunc(strings.ToLower)},
+		{name: "upper", min: 1, max: 1, eval: stringFunc(strings.ToUpper)},
+		{name: "trim", min: 1, max: 1, eval: stringFunc(strings.TrimSpace)},
+		{name: "len", min: 1, max: 1, eval: length},
+		{name: "substr", min: 2, max: 3, eval: substr},
+		{name: "contains", min: 2, max: 2, eval: stringTest(strings.Contains)},
+		{name: "starts_with", min: 2, max: 2, eval: stringTest(strings.HasPrefix)},
+		{name: "ends_with", min: 2, max: 2, eval: stringTest(strings.HasSuffix)},
+		{name: "replace", min: 3, max: 3, eval: replace},
+		{name: "number", min: 1, max: 1, eval: number},
+		{name: "string", min: 1, max: 1, eval: str},
+		{name: "abs", min: 1, max: 1, eval: numberFunc(math.Abs)},
+		{name: "round", min: 1, max: 1, eval: numberFunc(math.Round)},
+		{name: "floor", min: 1, max: 1, eval: numberFunc(math.Floor)},
+		{name: "ceil", min: 1, max: 1, eval: numberFunc(math.Ceil)},
+		{name: "parse_date", min: 1, max: 2, eval: parseDate},
+		{name: "format_date", min: 2, max: 2, eval: formatDate},
+		{name: "coalesce", min: 1, max: -1, lazy: coalesce},
+		{name: "if", min: 3, max: 3, lazy: ifFunc},
+		{name: "field", min: 1, max: 1, lazy: fieldFunc},
+	} {
+		functions[f.name] = f
+	}
+}
+
+func argString(val interface{}) (string, error) {
+	s, ok := val.(string)
+	if !ok {
+		return "", fmt.Errorf("expected a string, got %s", describe(val))
+	}
+	return s, nil
+}
+
+func argInt(val interface{}) (int, error) {
+	f, ok := toNumber(val)
+	if !ok || f != math.Trunc(f) {
+		return 0, fmt.Errorf("expected a whole number, got %s", describe(val))
+	}
+	return int(f), nil
+}
+
+func argTime(val interface{}) (time.Time, error) {
+	t, ok := val.(time.Time)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected a date, got %s (use parse_date to convert strings)", describe(val))
+	}
+	return t, nil
+}
+
+// toString converts a value to a string the way that string does.
+func toString(val interface{}) string {
+	switch val := val.(type) {
+	case string:
+		return val
+	case time.Time:
+		return val.Format(time.RFC3339)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	}
+	return fmt.Sprint(val)
+}
+
+// stringFunc returns a function of one string. Null results in null.
+func stringFunc(fn func(string) string) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		s, err := argString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return fn(s), nil
+	}
+}
+
+// stringTest returns a function of two strings that returns a boolean. Null results in null.
+func stringTest(fn func(string, string) bool) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if args[0] == nil || args[1] == nil {
+			return nil, nil
+		}
+		s, err := argString(args[0])
+		if err != nil {
+			return nil, err
+		}
+		sub, err := argString(args[1])
+		if err != nil {
+			return nil, err
+		}
+		return fn(s, sub), nil
+	}
+}
+
+// numberFunc returns a function of one number. Null results in null.
+func numberFunc(fn func(float64) float64) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		f, ok := toNumber(args[0])
+		if !ok {
+			return nil, fmt.Errorf("expected a number, got %s", describe(args[0]))
+		}
+		return fn(f), nil
+	}
+}
+
+// concat joins its arguments into a string, skipping nulls.
+func concat(args []interface{}) (interface{}, error) {
+	var b strings.Builder
+	for _, arg := range args {
+		if arg != nil {
+			b.WriteString(toString(arg))
+		}
+	}
+	return b.String(), nil
+}
+
+// length is the number of characters in a string, or the number of elements in an array or
+// object. Null has a length of 0.
+func length(args []interface{}) (interface{}, error) {
+	switch val := args[0].(type) {
+	case nil:
+		return float64(0), nil
+	case string:
+		return float64(utf8.RuneCountInString(val)), nil
+	}
+	switch v := reflect.ValueOf(args[0]); v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), nil
+	}
+	return nil, fmt.Errorf("expected a string, array or object, got %s", describe(args[0]))
+}
+
+// substr returns the characters of a string starting at the 0-based start, up to length of them.
+// It stops at the end of the string.
+func substr(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	s, err := argString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s)
+	start, err := argInt(args[1])
+	if err != nil {
+		return nil, err
+	}
+	end := len(runes)
+	if len(args) == 3 {
+		n, err := argInt(args[2])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("length can't be negative, got %d", n)
+		}
+		end = min(start+n, end)
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("start can't be negative, got %d", start)
+	}
+	if start >= end {
+		return "", nil
+	}
+	return string(runes[start:end]), nil
+}
+
+func replace(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	strs := make([]string, 3)
+	for i, arg := range args {
+		s, err := argString(arg)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = s
+	}
+	return strings.ReplaceAll(strs[0], strs[1], strs[2]), nil
+}
+
+// number converts a string or any numeric type to a number. Empty strings are null.
+func number(args []interface{}) (interface{}, error) {
+	if f, ok := toNumber(args[0]); ok {
+		return f, nil
+	}
+	switch val := args[0].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		s := strings.TrimSpace(val)
+		if s == "" {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("can't convert %s to a number", describe(val))
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("can't convert %s to a number", describe(args[0]))
+}
+
+func str(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	return toString(args[0]), nil
+}
+
+// parseDate parses a string into a date with a Go time layout, which defaults to RFC 3339.
+func parseDate(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	s, err := argString(args[0])
+	if err != nil {
+		return nil, err
+	}
+	layout := time.RFC3339
+	if len(args) == 2 {
+		if layout, err = argString(args[1]); err != nil {
+			return nil, err
+		}
+	}
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		return nil, fmt.Errorf("can't parse %s with layout %q", describe(s), layout)
+	}
+	return t, nil
+}
+
+// formatDate formats a date with a Go time layout.
+func formatDate(args []interface{}) (interface{}, error) {
+	if args[0] == nil {
+		return nil, nil
+	}
+	t, err := argTime(args[0])
+	if err != nil {
+		return nil, err
+	}
+	layout, err := argString(args[1])
+	if err != nil {
+		return nil, err
+	}
+	return t.Format(layout), nil
+}
+
+// coalesce returns its first argument that isn't null.
+func coalesce(row optimus.Row, args []node) (interface{}, error) {
+	for _, arg := range args {
+		val, err := arg.eval(row)
+		if err != nil || val != nil {
+			return val, err
+		}
+	}
+	return nil, nil
+}
+
+// ifFunc returns its second argument if its first is true, and its third otherwise.
+func ifFunc(row optimus.Row, args []node) (interface{}, error) {
+	cond, err := args[0].eval(row)
+	if err != nil {
+		return nil, err
+	}
+	b, err := truthy(args[0], cond)
+	if err != nil {
+		return nil, err
+	}
+	if b {
+		return args[1].eval(row)
+	}
+	return args[2].eval(row)
+}
+
+// fieldFunc returns the field with exactly the given name, for names that aren't valid in
+// expressions, like "first name" or "and".
+func fieldFunc(row optimus.Row, args []node) (interface{}, error) {
+	name, err := args[0].eval(row)
+	if err != nil {
+		return nil, err
+	}
+	s, err := argString(name)
+	if err != nil {
+		return nil, err
+	}
+	return row[s], nil
+}
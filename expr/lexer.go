@@ -0,0 +1,234 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Position is a location in an expression's source. Lines and columns start at 1, and columns
+// count characters, not bytes.
+type Position struct {
+	Line, Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Error is an error in an expression, either when it's compiled or when it's evaluated. Pos is
+// where in the expression the error is.
+type Error struct {
+	Pos Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+func errorf(pos Position, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	// text is the token as it appears in the source, except for strings, where it's the unquoted
+	// value.
+	text string
+	pos  Position
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("'%s'", t.text)
+}
+
+// operators are the operator tokens, longest first so that e.g. "<=" isn't lexed as "<".
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "+", "-", "*", "/", "%", "!", "(", ")", ","}
+
+type lexer struct {
+	src  string
+	off  int
+	line int
+	col  int
+}
+
+// lex splits an expression into tokens, ending with a tokenEOF.
+func lex(src string) ([]token, error) {
+	l := &lexer{src: src, line: 1, col: 1}
+	tokens := []token{}
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.kind == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) pos() Position {
+	return Position{Line: l.line, Column: l.col}
+}
+
+func (l *lexer) peek() rune {
+	if l.off >= len(l.src) {
+		return utf8.RuneError
+	}
+	r, _ := utf8.DecodeRuneInString(l.src[l.off:])
+	return r
+}
+
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.src[l.off:])
+	l.off += size
+	if r == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return r
+}
+
+func (l *lexer) next() (token, error) {
+	for l.off < len(l.src) && unicode.IsSpace(l.peek()) {
+		l.advance()
+	}
+	pos := l.pos()
+	if l.off >= len(l.src) {
+		return token{kind: tokenEOF, pos: pos}, nil
+	}
+	start := l.off
+	r := l.peek()
+	switch {
+	case isIdentStart(r):
+		return l.ident(pos)
+	case unicode.IsDigit(r) || (r == '.' && l.off+1 < len(l.src) && unicode.IsDigit(rune(l.src[l.off+1]))):
+		return l.number(pos)
+	case r == '"' || r == '\'':
+		return l.string(pos)
+	}
+	for _, op := range operators {
+		if strings.HasPrefix(l.src[start:], op) {
+			for range op {
+				l.advance()
+			}
+			return token{kind: tokenOperator, text: op, pos: pos}, nil
+		}
+	}
+	if r == '=' {
+		return token{}, errorf(pos, "unexpected '=', did you mean '=='?")
+	}
+	return token{}, errorf(pos, "unexpected character %q", r)
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r)
+}
+
+// ident lexes a name, which may be a dotted path like "address.city".
+func (l *lexer) ident(pos Position) (token, error) {
+	start := l.off
+	for l.off < len(l.src) {
+		if r := l.peek(); isIdentPart(r) {
+			l.advance()
+		} else if r == '.' {
+			dot := l.pos()
+			l.advance()
+			if l.off >= len(l.src) || !isIdentStart(l.peek()) {
+				return token{}, errorf(dot, "expected a field name after '.'")
+			}
+		} else {
+			break
+		}
+	}
+	return token{kind: tokenIdent, text: l.src[start:l.off], pos: pos}, nil
+}
+
+func (l *lexer) digits() {
+	for l.off < len(l.src) && unicode.IsDigit(l.peek()) {
+		l.advance()
+	}
+}
+
+func (l *lexer) number(pos Position) (token, error) {
+	start := l.off
+	l.digits()
+	if l.peek() == '.' {
+		l.advance()
+		l.digits()
+	}
+	if r := l.peek(); r == 'e' || r == 'E' {
+		l.advance()
+		if r := l.peek(); r == '+' || r == '-' {
+			l.advance()
+		}
+		l.digits()
+	}
+	text := l.src[start:l.off]
+	if _, err := strconv.ParseFloat(text, 64); err != nil {
+		return token{}, errorf(pos, "invalid number '%s'", text)
+	}
+	if l.off < len(l.src) && isIdentStart(l.peek()) {
+		return token{}, errorf(l.pos(), "unexpected %q after number", l.peek())
+	}
+	return token{kind: tokenNumber, text: text, pos: pos}, nil
+}
+
+// string lexes a string in single or double quotes. Backslash escapes are the same as Go's.
+func (l *lexer) string(pos Position) (token, error) {
+	quote := l.advance()
+	var b strings.Builder
+	for {
+		if l.off >= len(l.src) {
+			return token{}, errorf(pos, "unterminated string")
+		}
+		escPos, escOff := l.pos(), l.off
+		r := l.advance()
+		switch r {
+		case quote:
+			return token{kind: tokenString, text: b.String(), pos: pos}, nil
+		case '\n':
+			return token{}, errorf(pos, "unterminated string")
+		case '\\':
+			if l.off >= len(l.src) {
+				return token{}, errorf(pos, "unterminated string")
+			}
+			value, _, tail, err := strconv.UnquoteChar(l.src[escOff:], byte(quote))
+			if err != nil {
+				return token{}, errorf(escPos, "invalid escape in string")
+			}
+			for l.off < len(l.src)-len(tail) {
+				l.advance()
+			}
+			b.WriteRune(value)
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package expr
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Binding powers of the infix operators. Higher binds tighter.
+const (
+	precLowest = iota
+	precOr
+	precAnd
+	precNot
+	precCompare
+	precSum
+	precProduct
+	precUnary
+)
+
+var infixPrecedence = map[string]int{
+	"or": precOr, "||": precOr,
+	"and": precAnd, "&&": precAnd,
+	"==": precCompare, "!=": precCompare, "<": precCompare, "<=": precCompare, ">": precCompare, ">=": precCompare,
+	"+": precSum, "-": precSum,
+	"*": precProduct, "/": precProduct, "%": precProduct,
+}
+
+// keywords can't be used as field names, except with field("name").
+var keywords = map[string]bool{"and": true, "or": true, "not": true, "true": true, "false": true, "null": true}
+
+type parser struct {
+	tokens []token
+	i      int
+}
+
+// parse parses an expression into the root node of its syntax tree.
+func parse(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.expr(precLowest)
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, errorf(tok.pos, "expected an operator or end of expression, found %s", tok)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.i]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.i]
+	if tok.kind != tokenEOF {
+		p.i++
+	}
+	return tok
+}
+
+// infix returns the operator and its precedence if tok is an infix operator.
+func infix(tok token) (string, int, bool) {
+	if tok.kind != tokenOperator && tok.kind != tokenIdent {
+		return "", 0, false
+	}
+	prec, ok := infixPrecedence[tok.text]
+	return tok.text, prec, ok
+}
+
+// expr parses an expression whose operators all bind tighter than prec.
+func (p *parser) expr(prec int) (node, error) {
+	left, err := p.prefix()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		op, opPrec, ok := infix(tok)
+		if !ok || opPrec <= prec {
+			return left, nil
+		}
+		p.next()
+		right, err := p.expr(opPrec)
+		if err != nil {
+			return nil, err
+		}
+		if opPrec == precCompare {
+			if next, _, _ := infix(p.peek()); infixPrecedence[next] == precCompare {
+				return nil, errorf(p.peek().pos, "comparisons can't be chained, use 'and' to combine them")
+			}
+		}
+		switch op {
+		case "and", "&&", "or", "||":
+			left = &logical{at: tok.pos, and: op == "and" || op == "&&", left: left, right: right}
+		default:
+			left = &binary{at: tok.pos, op: op, left: left, right: right}
+		}
+	}
+}
+
+func (p *parser) prefix() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		f, _ := strconv.ParseFloat(tok.text, 64)
+		return &literal{at: tok.pos, value: f}, nil
+	case tokenString:
+		return &literal{at: tok.pos, value: tok.text}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true", "false":
+			return &literal{at: tok.pos, value: tok.text == "true"}, nil
+		case "null":
+			return &literal{at: tok.pos, value: nil}, nil
+		case "not":
+			return p.unary(tok, precNot)
+		case "and", "or":
+			return nil, errorf(tok.pos, "expected an expression, found %s", tok)
+		}
+		if p.peek().text == "(" && p.peek().kind == tokenOperator {
+			return p.call(tok)
+		}
+		return &field{at: tok.pos, path: tok.text}, nil
+	case tokenOperator:
+		switch tok.text {
+		case "!":
+			return p.unary(tok, precNot)
+		case "-":
+			return p.unary(tok, precUnary)
+		case "(":
+			n, err := p.expr(precLowest)
+			if err != nil {
+				return nil, err
+			}
+			if closing := p.next(); closing.text != ")" || closing.kind != tokenOperator {
+				return nil, errorf(closing.pos, "expected ')' to close '(' at %s, found %s", tok.pos, closing)
+			}
+			return n, nil
+		}
+	}
+	return nil, errorf(tok.pos, "expected an expression, found %s", tok)
+}
+
+func (p *parser) unary(op token, prec int) (node, error) {
+	operand, err := p.expr(prec)
+	if err != nil {
+		return nil, err
+	}
+	return &unary{at: op.pos, not: op.text != "-", operand: operand}, nil
+}
+
+func (p *parser) call(name token) (node, error) {
+	fn, ok := functions[strings.ToLower(name.text)]
+	if !ok {
+		return nil, errorf(name.pos, "unknown function '%s'", name.text)
+	}
+	open := p.next()
+	args := []node{}
+	if tok := p.peek(); tok.kind == tokenOperator && tok.text == ")" {
+		p.next()
+	} else {
+		for {
+			arg, err := p.expr(precLowest)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			tok := p.next()
+			if tok.kind == tokenOperator && tok.text == ")" {
+				break
+			}
+			if tok.kind != tokenOperator || tok.text != "," {
+				return nil, errorf(tok.pos, "expected ',' or ')' to close '(' at %s, found %s", open.pos, tok)
+			}
+		}
+	}
+	if err := fn.checkArgs(len(args)); err != "" {
+		return nil, errorf(name.pos, "%s", err)
+	}
+	return &call{at: name.pos, fn: fn, args: args}, nil
+}
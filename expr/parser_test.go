@@ -0,0 +1,46 @@
+package expr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var compileErrorTests = []struct {
+	src string
+	err string
+}{
+	{``, "1:1: expected an expression, found end of expression"},
+	{`1 +`, "1:4: expected an expression, found end of expression"},
+	{`(1 + 2`, "1:7: expected ')' to close '(' at 1:1, found end of expression"},
+	{`concat(a b)`, "1:10: expected ',' or ')' to close '(' at 1:7, found 'b'"},
+	{`a b`, "1:3: expected an operator or end of expression, found 'b'"},
+	{`a = 1`, "1:3: unexpected '=', did you mean '=='?"},
+	{`a < b < c`, "1:7: comparisons can't be chained, use 'and' to combine them"},
+	{`a and or b`, "1:7: expected an expression, found 'or'"},
+	{`lowr(name)`, "1:1: unknown function 'lowr'"},
+	{`lower()`, "1:1: lower takes 1 argument, got 0"},
+	{`substr(name)`, "1:1: substr takes 2 or 3 arguments, got 1"},
+	{`concat()`, "1:1: concat takes at least 1 argument, got 0"},
+	{`if(a, b)`, "1:1: if takes 3 arguments, got 2"},
+	{`"abc`, "1:1: unterminated string"},
+	{`'a\qb'`, "1:3: invalid escape in string"},
+	{`address.`, "1:8: expected a field name after '.'"},
+	{`12abc`, "1:3: unexpected 'a' after number"},
+	{`a $ b`, "1:3: unexpected character '$'"},
+	{"a and\n  (b or\n  c", "3:4: expected ')' to close '(' at 2:3, found end of expression"},
+	{"lower(\"é\") == \"é\" )", "1:19: expected an operator or end of expression, found ')'"},
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, compileErrorTest := range compileErrorTests {
+		_, err := Compile(compileErrorTest.src)
+		assert.EqualError(t, err, compileErrorTest.err, compileErrorTest.src)
+		assert.IsType(t, &Error{}, err, compileErrorTest.src)
+	}
+}
+
+func TestMustCompile(t *testing.T) {
+	assert.Equal(t, "a + 1", MustCompile("a + 1").String())
+	assert.Panics(t, func() { MustCompile("a +") })
+}
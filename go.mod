@@ -3,7 +3,7 @@ module github.com/Clever/optimus/v4
 go 1.21
 
 require (
-	github.com/facebookgo/errgroup v0.0.0-20160209021148-779c8d7ef069
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/stretchr/testify v1.6.1
 	gopkg.in/Clever/gearman.v1 v1.0.0
 	gopkg.in/fatih/set.v0 v0.1.0
@@ -11,10 +11,6 @@ require (
 
 require (
 	github.com/davecgh/go-spew v1.1.0 // indirect
-	github.com/facebookgo/ensure v0.0.0-20200202191622-63f1cf65ac4c // indirect
-	github.com/facebookgo/stack v0.0.0-20160209184415-751773369052 // indirect
-	github.com/facebookgo/subset v0.0.0-20200203212716-c811ad88dec4 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.1.0 // indirect
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
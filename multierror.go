@@ -0,0 +1,124 @@
+package optimus
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// MultiError collects the errors of a pipeline, instead of keeping only the first one, e.g. to
+// report every invalid Row in one run. It keeps up to a limit of the errors, and counts all of them
+// by type. It works with errors.Is and errors.As, which check each of the errors it kept. It's safe
+// to add errors from more than one goroutine.
+type MultiError struct {
+	limit  int
+	m      sync.Mutex
+	errs   []error
+	total  int
+	counts map[string]int
+}
+
+// NewMultiError returns an empty MultiError that keeps up to limit errors. If limit isn't positive,
+// it keeps all of them.
+func NewMultiError(limit int) *MultiError {
+	return &MultiError{limit: limit, counts: map[string]int{}}
+}
+
+// Add adds an error, unless it's nil. The errors of an error that wraps more than one, like
+// another MultiError or the result of errors.Join, are added one at a time, and the errors that
+// another MultiError only counted are counted too.
+func (e *MultiError) Add(err error) {
+	if err == nil {
+		return
+	}
+	if multi, ok := err.(*MultiError); ok {
+		// Keep the counts of the errors that it didn't keep.
+		errs, counts := multi.Unwrap(), multi.Counts()
+		e.m.Lock()
+		defer e.m.Unlock()
+		for _, err := range errs {
+			e.keep(err)
+		}
+		for typ, count := range counts {
+			e.total += count
+			e.counts[typ] += count
+		}
+		return
+	}
+	if multi, ok := err.(interface{ Unwrap() []error }); ok {
+		for _, err := range multi.Unwrap() {
+			e.Add(err)
+		}
+		return
+	}
+	e.m.Lock()
+	defer e.m.Unlock()
+	e.total++
+	e.counts[fmt.Sprintf("%T", err)]++
+	e.keep(err)
+}
+
+// keep keeps an error, unless the MultiError has reached its limit.
+func (e *MultiError) keep(err error) {
+	if e.limit <= 0 || len(e.errs) < e.limit {
+		e.errs = append(e.errs, err)
+	}
+}
+
+// Len returns the number of errors that were added, including the ones past the limit.
+func (e *MultiError) Len() int {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.total
+}
+
+// Counts returns the number of errors that were added of each type, e.g. "*optimus.PanicError",
+// including the ones past the limit.
+func (e *MultiError) Counts() map[string]int {
+	e.m.Lock()
+	defer e.m.Unlock()
+	counts := make(map[string]int, len(e.counts))
+	for typ, count := range e.counts {
+		counts[typ] = count
+	}
+	return counts
+}
+
+// Unwrap returns the errors that were kept.
+func (e *MultiError) Unwrap() []error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return append([]error(nil), e.errs...)
+}
+
+// Err returns nil if no errors were added, the error if only one was, and otherwise the
+// MultiError.
+func (e *MultiError) Err() error {
+	e.m.Lock()
+	defer e.m.Unlock()
+	switch e.total {
+	case 0:
+		return nil
+	case 1:
+		return e.errs[0]
+	}
+	return e
+}
+
+// Error lists the errors that were kept, and how many more there were, e.g.
+// "3 errors: bad id; bad name; and 1 more".
+func (e *MultiError) Error() string {
+	e.m.Lock()
+	defer e.m.Unlock()
+	messages := make([]string, 0, len(e.errs)+1)
+	for _, err := range e.errs {
+		messages = append(messages, err.Error())
+	}
+	if dropped := e.total - len(e.errs); dropped > 0 {
+		messages = append(messages, fmt.Sprintf("and %d more", dropped))
+	}
+	if e.total == 1 {
+		return "1 error: " + strings.Join(messages, "; ")
+	}
+	return fmt.Sprintf("%d errors: %s", e.total, strings.Join(messages, "; "))
+}
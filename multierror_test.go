@@ -0,0 +1,87 @@
+package optimus
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type rowError struct {
+	i int
+}
+
+func (e rowError) Error() string {
+	return fmt.Sprintf("bad row %d", e.i)
+}
+
+func TestMultiError(t *testing.T) {
+	failed := errors.New("failed")
+	errs := NewMultiError(3)
+	assert.Nil(t, errs.Err())
+	errs.Add(nil)
+	errs.Add(failed)
+	assert.Equal(t, failed, errs.Err())
+	assert.Equal(t, "1 error: failed", errs.Error())
+
+	errs.Add(errors.Join(rowError{1}, rowError{2}))
+	errs.Add(&PanicError{Value: "panicked"})
+	errs.Add(rowError{3})
+	assert.Equal(t, errs, errs.Err())
+	assert.Equal(t, 5, errs.Len())
+	assert.Equal(t, []error{failed, rowError{1}, rowError{2}}, errs.Unwrap())
+	assert.Equal(t, map[string]int{
+		"*errors.errorString": 1,
+		"optimus.rowError":    3,
+		"*optimus.PanicError": 1,
+	}, errs.Counts())
+	assert.Equal(t, "5 errors: failed; bad row 1; bad row 2; and 2 more", errs.Error())
+
+	assert.True(t, errors.Is(errs, failed))
+	var rowErr rowError
+	assert.True(t, errors.As(errs, &rowErr))
+	assert.Equal(t, rowError{1}, rowErr)
+	// Errors past the limit are only counted.
+	var panicErr *PanicError
+	assert.False(t, errors.As(errs, &panicErr))
+
+	unlimited := NewMultiError(0)
+	unlimited.Add(errs)
+	assert.Equal(t, 5, unlimited.Len())
+	assert.Equal(t, errs.Counts(), unlimited.Counts())
+	assert.Len(t, unlimited.Unwrap(), 3)
+}
+
+// failingTransform sends every Row, and then returns an error.
+func failingTransform(err error) TransformFunc {
+	return func(in <-chan Row, out chan<- Row) error {
+		for row := range in {
+			out <- row
+		}
+		return err
+	}
+}
+
+func TestCollectErrors(t *testing.T) {
+	first, second := errors.New("first"), errors.New("second")
+
+	table := Transform(newSliceTable(numberedRows(5)...), failingTransform(first), CollectErrors(10))
+	table = Transform(table, failingTransform(second), CollectErrors(10))
+	for range table.Rows() {
+	}
+	assert.IsType(t, &MultiError{}, table.Err())
+	assert.Equal(t, []error{second, first}, table.Err().(*MultiError).Unwrap())
+
+	// Without CollectErrors, only the first error is kept.
+	table = Transform(newSliceTable(numberedRows(5)...), failingTransform(first))
+	table = Transform(table, failingTransform(second))
+	for range table.Rows() {
+	}
+	assert.Equal(t, second, table.Err())
+
+	table = Transform(newSliceTable(numberedRows(5)...), failingTransform(nil), CollectErrors(10))
+	for range table.Rows() {
+	}
+	assert.Nil(t, table.Err())
+}
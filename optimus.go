@@ -25,60 +25,153 @@ type Row map[string]interface{}
 // TransformFunc is a function that can be applied to a Table to transform it. It should receive the
 // Rows from in and may send any number of Rows to out. It should not return until it has finished
 // all work (received all the Rows it's going to receive, sent all the Rows it's going to send).
+// A TransformFunc may return without receiving all the Rows from in, e.g. once it has sent all
+// the Rows it needs to. The upstream Table is then Stopped, and its remaining Rows are discarded.
+// A TransformFunc that reads from Tables other than in should stop them once Stopped(out) is
+// closed.
 type TransformFunc func(in <-chan Row, out chan<- Row) error
 
+// TypedTable is a Table of values of type T instead of Rows. Every Table is a TypedTable[Row].
+// See Decode and Encode for converting between Tables and TypedTables of structs.
+type TypedTable[T any] interface {
+	// Rows returns a channel that provides the values in the table.
+	Rows() <-chan T
+	// Err returns the first non-EOF error that was encountered by the Table.
+	Err() error
+	// Stop signifies that a Table should stop sending values down its channel. See Table.Stop.
+	Stop()
+}
+
+// TypedTransformFunc is a TransformFunc that receives values of type T and sends values of type U.
+type TypedTransformFunc[T, U any] func(in <-chan T, out chan<- U) error
+
+// TransformOption configures how Transform runs a TransformFunc.
+type TransformOption func(*transformOptions)
+
+type transformOptions struct {
+	bufferSize int
+	stageName  string
+	// errLimit is the limit of a MultiError of the Table's errors, if collectErrs is true.
+	collectErrs bool
+	errLimit    int
+}
+
+// BufferSize buffers the channels between a Transform and its TransformFunc, and the Rows channel
+// of the Table it returns, with room for size Rows each. By default they're unbuffered, so every
+// Row is handed off between goroutines one at a time. Buffering lets the stages of a pipeline run
+// ahead of each other, at the cost of holding more Rows in memory. A consumer may still receive
+// the Rows in the buffer after the Table is stopped.
+func BufferSize(size int) TransformOption {
+	return func(options *transformOptions) {
+		options.bufferSize = max(size, 0)
+	}
+}
+
+// StageName names the stage of the pipeline that a Transform is, e.g. "normalize grades". If its
+// TransformFunc panics, the name is the Stage of the PanicError.
+func StageName(name string) TransformOption {
+	return func(options *transformOptions) {
+		options.stageName = name
+	}
+}
+
+// CollectErrors makes the Err of a Transform's Table a *MultiError of its TransformFunc's error
+// and its source's error, with up to limit errors, instead of only the first one. The errors of
+// a source that collects them too are added one at a time, so a pipeline whose stages all collect
+// errors reports every error that any of them returned. See transforms.Validate for a TransformFunc
+// that returns an error for every invalid Row.
+func CollectErrors(limit int) TransformOption {
+	return func(options *transformOptions) {
+		options.collectErrs = true
+		options.errLimit = limit
+	}
+}
+
 // Transform returns a new Table that provides all the Rows of the input Table transformed with the TransformFunc.
-func Transform(source Table, transform TransformFunc) Table {
-	return newTransformedTable(source, transform)
+// If the TransformFunc panics, the panic is recovered, and the Table fails with a *PanicError.
+func Transform(source Table, transform TransformFunc, opts ...TransformOption) Table {
+	return newTransformedTable[Row, Row](source, TypedTransformFunc[Row, Row](transform), opts)
+}
+
+// TypedTransform returns a new TypedTable that provides all the values of the input TypedTable
+// transformed with the TypedTransformFunc. It works exactly like Transform.
+func TypedTransform[T, U any](source TypedTable[T], transform TypedTransformFunc[T, U], opts ...TransformOption) TypedTable[U] {
+	return newTransformedTable(source, transform, opts)
 }
 
-type transformedTable struct {
-	source  Table
+type transformedTable[T, U any] struct {
+	source  TypedTable[T]
 	err     error
-	rows    chan Row
+	rows    chan U
 	m       sync.Mutex
 	stopped bool
+	stopCh  chan struct{}
 }
 
-func (t *transformedTable) Rows() <-chan Row {
+func (t *transformedTable[T, U]) Rows() <-chan U {
 	return t.rows
 }
 
-func (t *transformedTable) Err() error {
+func (t *transformedTable[T, U]) Err() error {
+	t.m.Lock()
+	defer t.m.Unlock()
 	return t.err
 }
 
-func (t *transformedTable) Stop() {
+func (t *transformedTable[T, U]) setErr(err error) {
 	t.m.Lock()
-	stopped := t.stopped
+	t.err = err
 	t.m.Unlock()
-	if stopped {
+}
+
+func (t *transformedTable[T, U]) Stop() {
+	t.m.Lock()
+	if t.stopped {
+		t.m.Unlock()
 		return
 	}
-	t.m.Lock()
 	t.stopped = true
+	close(t.stopCh)
 	t.m.Unlock()
 	t.source.Stop()
 }
 
-func drain(c <-chan Row) {
+// stopChs maps the out channel of each running TransformFunc to the stop channel of its Table.
+var stopChs sync.Map
+
+// Stopped returns a channel that's closed once the Table that a running TransformFunc's out
+// channel belongs to is stopped, e.g. by its consumer. The Rows that the TransformFunc sends
+// after that are discarded, so a TransformFunc that reads from Tables other than in should stop
+// them, and return. If out doesn't belong to a Table, the channel is never closed.
+func Stopped[U any](out chan<- U) <-chan struct{} {
+	if stopCh, ok := stopChs.Load(out); ok {
+		return stopCh.(chan struct{})
+	}
+	return nil
+}
+
+func drain[T any](c <-chan T) {
 	for range c {
 		// Drain everything left in the channel
 	}
 }
 
-func (t *transformedTable) start(transform TransformFunc) {
+func (t *transformedTable[T, U]) start(transform TypedTransformFunc[T, U], options transformOptions) {
 	// A level of indirection is necessary between the i/o channels and the TransformFunc so that
 	// the TransformFunc doesn't need to know about the stop state of any of the Tables.
-	in := make(chan Row)
-	out := make(chan Row)
+	in := make(chan T, options.bufferSize)
+	out := make(chan U, options.bufferSize)
 	errChan := make(chan error)
-	doneChan := make(chan struct{})
+	outputDone := make(chan struct{})
+	inputDone := make(chan struct{})
+	transformDone := make(chan struct{})
 
 	stop := func() {
 		t.Stop()
 		drain(t.source.Rows())
 		drain(out)
+		// Make sure nothing is still sending on the Table's channel before closing it
+		<-outputDone
 		close(t.rows)
 	}
 	defer stop()
@@ -87,15 +180,24 @@ func (t *transformedTable) start(transform TransformFunc) {
 	go func() {
 		defer close(errChan)
 		defer close(out)
-		if err := transform(in, out); err != nil {
+		defer close(transformDone)
+		stopChs.Store((chan<- U)(out), t.stopCh)
+		defer stopChs.Delete((chan<- U)(out))
+		err := Recover(func() error {
+			return transform(in, out)
+		})
+		// Name the stage if its TransformFunc panicked, or one of its goroutines that it ran with
+		// Recover.
+		if panicErr, ok := err.(*PanicError); ok && panicErr.Stage == "" {
+			panicErr.Stage = options.stageName
+		}
+		if err != nil {
 			errChan <- err
 		}
 	}()
 	// Copy from the TransformFunc's out channel to the Table's out channel, then signal done
 	go func() {
-		defer func() {
-			doneChan <- struct{}{}
-		}()
+		defer close(outputDone)
 		for row := range out {
 			t.m.Lock()
 			stopped := t.stopped
@@ -103,43 +205,81 @@ func (t *transformedTable) start(transform TransformFunc) {
 			if stopped {
 				continue
 			}
-			t.rows <- row
+			select {
+			case t.rows <- row:
+			case <-t.stopCh:
+				// The consumer may have stopped reading
+			}
 		}
 	}()
 
 	// Copy from the Table's source to the TransformFunc's in channel, then signal done
 	go func() {
-		defer func() {
-			doneChan <- struct{}{}
-		}()
+		defer close(inputDone)
 		defer close(in)
+		transformReturned := false
 		for row := range t.source.Rows() {
 			t.m.Lock()
 			stopped := t.stopped
 			t.m.Unlock()
-			if stopped {
+			if stopped || transformReturned {
 				continue
 			}
-			in <- row
+			select {
+			case in <- row:
+			case <-transformDone:
+				// The TransformFunc won't receive any more Rows, so there's no reason for the
+				// source to keep sending them.
+				transformReturned = true
+				t.source.Stop()
+			}
 		}
 	}()
 	for err := range errChan {
-		t.err = err
+		if !options.collectErrs {
+			t.setErr(err)
+			return
+		}
+		// Send the Rows that the TransformFunc sent before it failed, and wait for the stopped
+		// source to finish, so that its error is collected too.
+		<-outputDone
+		t.Stop()
+		<-inputDone
+		t.collectErrs(options.errLimit, err, t.source.Err())
 		return
 	}
 	// Wait for all channels to finish
-	<-doneChan // Once to make sure we've consumed the output of the TransformFunc
-	<-doneChan // Once to make sure we've consumed the output of the source Table
-	if t.source.Err() != nil {
-		t.err = t.source.Err()
+	<-outputDone // Make sure we've consumed the output of the TransformFunc
+	<-inputDone  // Make sure we've consumed the output of the source Table
+	if options.collectErrs {
+		t.collectErrs(options.errLimit, t.source.Err())
+	} else if err := t.source.Err(); err != nil {
+		t.setErr(err)
 	}
 }
 
-func newTransformedTable(source Table, transform TransformFunc) Table {
-	table := &transformedTable{
+// collectErrs sets the Table's error to a MultiError of errs, if any of them aren't nil.
+func (t *transformedTable[T, U]) collectErrs(limit int, errs ...error) {
+	multi := NewMultiError(limit)
+	for _, err := range errs {
+		multi.Add(err)
+	}
+	if multi.Len() > 0 {
+		t.setErr(multi)
+	}
+}
+
+func newTransformedTable[T, U any](source TypedTable[T], transform TypedTransformFunc[T, U],
+	opts []TransformOption) *transformedTable[T, U] {
+	var options transformOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	table := &transformedTable[T, U]{
 		source: source,
-		rows:   make(chan Row),
+		rows:   make(chan U, options.bufferSize),
+		stopCh: make(chan struct{}),
 	}
-	go table.start(transform)
+	go table.start(transform, options)
 	return table
 }
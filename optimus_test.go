@@ -0,0 +1,33 @@
+package optimus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStopped tests that a TransformFunc finds out when its Table is stopped, even if it isn't
+// reading from in.
+func TestStopped(t *testing.T) {
+	stopped := make(chan struct{})
+	table := Transform(newSliceTable(Row{"i": 0}), func(in <-chan Row, out chan<- Row) error {
+		for row := range in {
+			out <- row
+		}
+		<-Stopped(out)
+		close(stopped)
+		return nil
+	})
+	<-table.Rows()
+	table.Stop()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("expected the TransformFunc to be told that its Table was stopped")
+	}
+	for range table.Rows() {
+	}
+
+	assert.Nil(t, Stopped(make(chan Row)))
+}
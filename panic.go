@@ -0,0 +1,55 @@
+package optimus
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is the error of a Table whose TransformFunc or source panicked. The panic is
+// recovered, so that it fails the pipeline like any other error instead of crashing the process.
+type PanicError struct {
+	// Stage is the name of the stage that panicked, if it's known: the name that was given with
+	// StageName, or the package of a source, e.g. "sources/csv".
+	Stage string
+	// Value is the value that was passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	if e.Stage == "" {
+		return fmt.Sprintf("panic: %v\n\n%s", e.Value, e.Stack)
+	}
+	return fmt.Sprintf("panic in %s: %v\n\n%s", e.Stage, e.Value, e.Stack)
+}
+
+// Unwrap returns the value that was passed to panic, if it's an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// NewPanicError returns a PanicError for a recovered panic. It should be called by the deferred
+// function that recovered it, so that the stack trace is the stack of the panic, e.g.
+//
+//	defer func() {
+//		if r := recover(); r != nil {
+//			err = optimus.NewPanicError(r)
+//		}
+//	}()
+func NewPanicError(value interface{}) *PanicError {
+	return &PanicError{Value: value, Stack: debug.Stack()}
+}
+
+// Recover calls fn, and returns a PanicError if it panics. Transform already recovers the panics
+// of TransformFuncs, but a TransformFunc that starts its own goroutines should run them with
+// Recover, since a panic can only be recovered by the goroutine that panicked.
+func Recover(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = NewPanicError(r)
+		}
+	}()
+	return fn()
+}
@@ -0,0 +1,52 @@
+package optimus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransformPanic(t *testing.T) {
+	for _, name := range []string{"", "explode"} {
+		var opts []TransformOption
+		if name != "" {
+			opts = append(opts, StageName(name))
+		}
+		source := newSliceTable(numberedRows(10)...)
+		table := Transform(source, func(in <-chan Row, out chan<- Row) error {
+			for row := range in {
+				if row["i"] == 3 {
+					panic("bad row")
+				}
+				out <- row
+			}
+			return nil
+		}, opts...)
+		for range table.Rows() {
+		}
+
+		var panicErr *PanicError
+		if assert.True(t, errors.As(table.Err(), &panicErr)) {
+			assert.Equal(t, name, panicErr.Stage)
+			assert.Equal(t, "bad row", panicErr.Value)
+			assert.Contains(t, string(panicErr.Stack), "panic_test.go")
+		}
+		if name == "" {
+			assert.Regexp(t, "^panic: bad row\n", table.Err().Error())
+		} else {
+			assert.Regexp(t, "^panic in explode: bad row\n", table.Err().Error())
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	assert.NoError(t, Recover(func() error { return nil }))
+	failed := errors.New("failed")
+	assert.Equal(t, failed, Recover(func() error { return failed }))
+
+	err := Recover(func() error { panic(failed) })
+	assert.IsType(t, &PanicError{}, err)
+	assert.True(t, errors.Is(err, failed))
+	assert.Nil(t, Recover(func() error { panic("not an error") }).(*PanicError).Unwrap())
+}
@@ -39,6 +39,7 @@ type Scanner struct {
 	start        int       // First non-processed byte in buf.
 	end          int       // End of data in buf.
 	err          error     // Sticky error.
+	done         bool      // Scan has returned false, so it always will.
 }
 
 // SplitFunc is the signature of the split function used to tokenize the
@@ -113,6 +114,10 @@ func (s *Scanner) Text() string {
 // occurred during scanning, except that if it was io.EOF, Err
 // will return nil.
 func (s *Scanner) Scan() bool {
+	// Once scanning has stopped, it mustn't return what's left in the buffer.
+	if s.done {
+		return false
+	}
 	// Loop until we have a token.
 	for {
 		// See if we can get a token with what we already have.
@@ -120,9 +125,11 @@ func (s *Scanner) Scan() bool {
 			advance, token, err := s.split(s.buf[s.start:s.end], s.err != nil)
 			if err != nil {
 				s.setErr(err)
+				s.done = true
 				return false
 			}
 			if !s.advance(advance) {
+				s.done = true
 				return false
 			}
 			s.token = token
@@ -136,6 +143,7 @@ func (s *Scanner) Scan() bool {
 			// Shut it down.
 			s.start = 0
 			s.end = 0
+			s.done = true
 			return false
 		}
 		// Must read more data.
@@ -150,6 +158,7 @@ func (s *Scanner) Scan() bool {
 		if s.end == len(s.buf) {
 			if len(s.buf) >= s.maxTokenSize {
 				s.setErr(ErrTooLong)
+				s.done = true
 				return false
 			}
 			newSize := len(s.buf) * 2
@@ -0,0 +1,159 @@
+package scanner
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// chunkReader reads at most size bytes at a time, so that tokens are split across reads.
+type chunkReader struct {
+	r    io.Reader
+	size int
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(p) > c.size {
+		p = p[:c.size]
+	}
+	return c.r.Read(p)
+}
+
+// scanDelimited returns a SplitFunc like ScanLines that splits on delim, and doesn't drop '\r's.
+func scanDelimited(delim byte) SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if atEOF && len(data) == 0 {
+			return 0, nil, nil
+		}
+		if i := bytes.IndexByte(data, delim); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// misbehave returns a SplitFunc like ScanLines that advances by advance(data) instead when a line
+// contains trigger.
+func misbehave(trigger byte, advance func(data []byte) int) SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		n, token, err := ScanLines(data, atEOF)
+		if token != nil && bytes.IndexByte(token, trigger) >= 0 {
+			return advance(data), token, err
+		}
+		return n, token, err
+	}
+}
+
+// splitParts splits data on delim like scanDelimited.
+func splitParts(data []byte, delim byte) [][]byte {
+	parts := bytes.Split(data, []byte{delim})
+	if len(parts[len(parts)-1]) == 0 {
+		parts = parts[:len(parts)-1]
+	}
+	return parts
+}
+
+func FuzzScanner(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"a\nb\n",
+		"\xef\xbb\xbfa,b\r\nc,d\r\n",
+		"no newline at the end",
+		"\n\n\r\n\r\r\n",
+		"\xff\xfe\xc3\x28 \xe2\x82   words　here",
+		strings.Repeat("a", 70000) + "\nb",
+		"bad! line\n",
+		"far? line\n",
+	} {
+		f.Add([]byte(seed), uint8(0), uint16(0), uint8(0))
+	}
+	f.Fuzz(func(t *testing.T, data []byte, mode uint8, maxTokenSize uint16, readSize uint8) {
+		s := NewScanner(&chunkReader{r: bytes.NewReader(data), size: int(readSize) + 1})
+		if maxTokenSize > 0 {
+			s.maxTokenSize = int(maxTokenSize)
+		}
+		var expected [][]byte
+		var bad byte
+		switch mode % 7 {
+		case 0:
+			s.Split(ScanLines)
+			for _, line := range splitParts(data, '\n') {
+				expected = append(expected, dropCR(line))
+			}
+		case 1:
+			s.Split(ScanBytes)
+			for i := range data {
+				expected = append(expected, data[i:i+1])
+			}
+		case 2:
+			s.Split(ScanRunes)
+			for _, r := range string(data) {
+				expected = append(expected, []byte(string(r)))
+			}
+		case 3:
+			s.Split(ScanWords)
+			expected = bytes.Fields(data)
+		case 4:
+			s.Split(scanDelimited(','))
+			expected = splitParts(data, ',')
+		case 5:
+			s.Split(misbehave('!', func([]byte) int { return -1 }))
+			bad = '!'
+		case 6:
+			s.Split(misbehave('?', func(data []byte) int { return len(data) + 1 }))
+			bad = '?'
+		}
+
+		var tokens [][]byte
+		for s.Scan() {
+			// Tokens are only valid until the next Scan.
+			tokens = append(tokens, append([]byte{}, s.Bytes()...))
+			// Memory is bounded by maxTokenSize, except for the initial buffer.
+			if len(s.buf) > max(s.maxTokenSize, 4096) {
+				t.Fatalf("buffer grew to %d bytes, past the maximum of %d", len(s.buf), s.maxTokenSize)
+			}
+		}
+		err := s.Err()
+		// Scanning stops for good, and Err doesn't change.
+		assert.False(t, s.Scan())
+		assert.Equal(t, err, s.Err())
+
+		switch err {
+		case nil:
+			if bad != 0 {
+				assert.NotContains(t, string(data), string(bad))
+			} else if len(expected) == 0 {
+				assert.Empty(t, tokens)
+			} else {
+				assert.Equal(t, expected, tokens)
+			}
+		case ErrTooLong:
+			assert.Greater(t, len(data), s.maxTokenSize)
+		case ErrNegativeAdvance:
+			assert.Equal(t, byte('!'), bad)
+			assert.Contains(t, string(data), "!")
+		case ErrAdvanceTooFar:
+			assert.Equal(t, byte('?'), bad)
+			assert.Contains(t, string(data), "?")
+		default:
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+}
+
+// TestScanAfterErrTooLong tests that Scan keeps returning false after a token is too long, instead
+// of returning what's left in the buffer. The line has to be longer than the initial buffer.
+func TestScanAfterErrTooLong(t *testing.T) {
+	s := NewScanner(&chunkReader{r: strings.NewReader(strings.Repeat("a", 5000) + "\nb"), size: 35})
+	s.maxTokenSize = 89
+	assert.False(t, s.Scan())
+	assert.Equal(t, ErrTooLong, s.Err())
+	assert.False(t, s.Scan())
+	assert.Equal(t, ErrTooLong, s.Err())
+}
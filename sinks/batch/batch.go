@@ -0,0 +1,64 @@
+package batch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/transforms"
+)
+
+// Config configures how a batch Sink groups Rows and retries failed batches.
+type Config struct {
+	// Size is the largest number of Rows in a batch.
+	Size int
+	// Interval is the longest a Row waits for its batch to fill up before the batch is written.
+	// Zero means batches are only written when they're full or the Table is done.
+	Interval time.Duration
+	// Attempts is the number of times a batch is written before giving up. Zero means once.
+	Attempts int
+	// Backoff is how long to wait before the first retry. It doubles after every retry.
+	Backoff time.Duration
+}
+
+// New returns a Sink that groups the Rows of a Table into batches and calls write with each
+// batch, in order. The last batch is written even if it isn't full. If write returns an error,
+// it's retried according to the Config, and if it keeps failing the Sink stops the Table and
+// returns the last error.
+func New(config Config, write func([]optimus.Row) error) optimus.Sink {
+	return func(source optimus.Table) error {
+		batches := optimus.Transform(source, transforms.Batch(config.Size, config.Interval))
+		defer func() {
+			batches.Stop()
+			for range batches.Rows() {
+				// Drain the batches so that nothing is left blocked sending them
+			}
+		}()
+		for batch := range batches.Rows() {
+			rows := batch["rows"].([]optimus.Row)
+			if err := writeWithRetries(config, rows, write); err != nil {
+				return err
+			}
+		}
+		return batches.Err()
+	}
+}
+
+func writeWithRetries(config Config, rows []optimus.Row, write func([]optimus.Row) error) error {
+	attempts := config.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := config.Backoff
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = write(rows); err == nil {
+			return nil
+		}
+		if attempt < attempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return fmt.Errorf("writing a batch of %d rows failed after %d attempts: %w", len(rows), attempts, err)
+}
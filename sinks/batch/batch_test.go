@@ -0,0 +1,59 @@
+package batch
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	errorSource "github.com/Clever/optimus/v4/sources/error"
+	"github.com/Clever/optimus/v4/sources/infinite"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+var rows = []optimus.Row{{"i": 0}, {"i": 1}, {"i": 2}, {"i": 3}, {"i": 4}}
+
+func TestBatchSink(t *testing.T) {
+	batches := [][]optimus.Row{}
+	err := New(Config{Size: 2}, func(batch []optimus.Row) error {
+		batches = append(batches, batch)
+		return nil
+	})(slice.New(rows))
+	assert.Nil(t, err)
+	assert.Equal(t, [][]optimus.Row{rows[0:2], rows[2:4], rows[4:5]}, batches)
+}
+
+func TestBatchSinkRetries(t *testing.T) {
+	calls := 0
+	err := New(Config{Size: 10, Attempts: 3}, func(batch []optimus.Row) error {
+		calls++
+		if calls < 3 {
+			return errors.New("temporary error")
+		}
+		assert.Equal(t, rows, batch)
+		return nil
+	})(slice.New(rows))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestBatchSinkFails(t *testing.T) {
+	source := infinite.New()
+	calls := 0
+	err := New(Config{Size: 10, Attempts: 2}, func(batch []optimus.Row) error {
+		calls++
+		return errors.New("permanent error")
+	})(source)
+	assert.EqualError(t, err, "writing a batch of 10 rows failed after 2 attempts: permanent error")
+	assert.Equal(t, 2, calls)
+	tests.Consumed(t, source)
+}
+
+func TestBatchSinkSourceError(t *testing.T) {
+	err := New(Config{Size: 10}, func(batch []optimus.Row) error {
+		t.Fatal("never expected write to be called")
+		return nil
+	})(errorSource.New(errors.New("source error")))
+	assert.EqualError(t, err, "source error")
+}
@@ -54,5 +54,5 @@ func TestAlphabetical(t *testing.T) {
 func TestCSVSinkError(t *testing.T) {
 	source := errorSource.New(errors.New("failed"))
 	assert.EqualError(t, New(&bytes.Buffer{})(source), "failed")
-	assert.True(t, source.Stopped)
+	assert.True(t, source.WasStopped())
 }
@@ -0,0 +1,116 @@
+package gearman
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/transforms"
+	"gopkg.in/Clever/gearman.v1"
+	"gopkg.in/Clever/gearman.v1/job"
+	gearmanUtils "gopkg.in/Clever/gearman.v1/utils"
+)
+
+// Config configures how a Gearman Sink submits jobs.
+type Config struct {
+	// Function is the name of the Gearman function that runs each job.
+	Function string
+	// BatchSize is the number of Rows in each job. Zero means one.
+	BatchSize int
+	// Concurrency is the number of jobs that run at once. Zero means one.
+	Concurrency int
+	// Encode returns the workload of a job with the given Rows. By default, a job with one Row has
+	// the Row as a JSON object, and a larger batch has a JSON array of them.
+	Encode func([]optimus.Row) ([]byte, error)
+	// ErrorLimit is the largest number of failures that are returned. The rest are only counted.
+	// Zero means all of them are returned.
+	ErrorLimit int
+}
+
+// JobError is the failure of a job.
+type JobError struct {
+	Function string
+	// Rows are the Rows in the job's workload.
+	Rows []optimus.Row
+	// Warnings are the warnings that the job sent.
+	Warnings []byte
+}
+
+func (e *JobError) Error() string {
+	return fmt.Sprintf("gearman job '%s' failed with warnings: %s", e.Function, e.Warnings)
+}
+
+// discard is a WriteCloser that discards the data that a job sends.
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (discard) Close() error {
+	return nil
+}
+
+func encodeJSON(rows []optimus.Row) ([]byte, error) {
+	if len(rows) == 1 {
+		return json.Marshal(rows[0])
+	}
+	return json.Marshal(rows)
+}
+
+// submit runs a job with the Rows, and waits for it to finish.
+func submit(client gearman.Client, config Config, rows []optimus.Row) error {
+	workload, err := config.Encode(rows)
+	if err != nil {
+		return err
+	}
+	warnings := gearmanUtils.NewBuffer()
+	j, err := client.Submit(config.Function, workload, discard{}, warnings)
+	if err != nil {
+		return err
+	}
+	if j.Run() == job.Failed {
+		return &JobError{Function: config.Function, Rows: rows, Warnings: warnings.Bytes()}
+	}
+	return nil
+}
+
+// New returns a Sink that submits a Gearman job for each Row of a Table, or for each batch of
+// BatchSize Rows made with transforms.Batch, and waits for them to finish. A job that fails
+// doesn't stop the Sink: it submits every job, and then returns the failures, the errors that kept
+// jobs from being submitted, and the Table's error. If there was more than one, it returns an
+// *optimus.MultiError of them, with up to ErrorLimit errors. The data that jobs send is discarded.
+func New(client gearman.Client, config Config) optimus.Sink {
+	if config.Encode == nil {
+		config.Encode = encodeJSON
+	}
+	size := max(config.BatchSize, 1)
+	return func(source optimus.Table) error {
+		batches := optimus.Transform(source, transforms.Batch(size, 0))
+		defer batches.Stop()
+		errs := optimus.NewMultiError(config.ErrorLimit)
+
+		jobs := make(chan []optimus.Row)
+		wg := sync.WaitGroup{}
+		for i := 0; i < max(config.Concurrency, 1); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for rows := range jobs {
+					errs.Add(optimus.Recover(func() error {
+						return submit(client, config, rows)
+					}))
+				}
+			}()
+		}
+
+		for batch := range batches.Rows() {
+			jobs <- batch["rows"].([]optimus.Row)
+		}
+		close(jobs)
+		wg.Wait()
+		errs.Add(batches.Err())
+		return errs.Err()
+	}
+}
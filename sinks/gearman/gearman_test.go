@@ -0,0 +1,134 @@
+package gearman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	errorSource "github.com/Clever/optimus/v4/sources/error"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gopkg.in/Clever/gearman.v1/job"
+	"gopkg.in/Clever/gearman.v1/packet"
+)
+
+// mockClient runs each job that's submitted to it, and fails the ones whose workload contains
+// "fail".
+type mockClient struct {
+	*mock.Mock
+	m         sync.Mutex
+	running   int
+	most      int
+	workloads []string
+}
+
+func (c *mockClient) Close() error {
+	return nil
+}
+
+func (c *mockClient) Submit(fn string, payload []byte, data, warnings io.WriteCloser) (job.Job, error) {
+	args := c.Mock.Called(fn, payload, data, warnings)
+	if err := args.Error(1); err != nil {
+		return nil, err
+	}
+	c.m.Lock()
+	c.running++
+	c.most = max(c.most, c.running)
+	c.workloads = append(c.workloads, string(payload))
+	c.m.Unlock()
+
+	packets := make(chan *packet.Packet)
+	j := job.New("", data, warnings, packets)
+	go func() {
+		defer close(packets)
+		time.Sleep(time.Millisecond)
+		c.m.Lock()
+		c.running--
+		c.m.Unlock()
+		if bytes.Contains(payload, []byte("fail")) {
+			packets <- handlePacket("", packet.WorkWarning, [][]byte{payload})
+			packets <- handlePacket("", packet.WorkFail, nil)
+		} else {
+			packets <- handlePacket("", packet.WorkData, [][]byte{[]byte("ignored")})
+			packets <- handlePacket("", packet.WorkComplete, nil)
+		}
+	}()
+	return j, nil
+}
+
+func handlePacket(handle string, kind int, arguments [][]byte) *packet.Packet {
+	if arguments == nil {
+		arguments = [][]byte{}
+	}
+	arguments = append([][]byte{[]byte(handle)}, arguments...)
+	return &packet.Packet{
+		Type:      packet.Type(kind),
+		Arguments: arguments,
+	}
+}
+
+func newMockClient() *mockClient {
+	c := &mockClient{Mock: &mock.Mock{}}
+	c.On("Submit", "function", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	return c
+}
+
+var rows = []optimus.Row{{"i": 0}, {"i": 1}, {"i": 2}, {"i": 3}, {"i": 4}}
+
+func TestGearmanSink(t *testing.T) {
+	c := newMockClient()
+	err := New(c, Config{Function: "function"})(slice.New(rows))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{`{"i":0}`, `{"i":1}`, `{"i":2}`, `{"i":3}`, `{"i":4}`}, c.workloads)
+	assert.Equal(t, 1, c.most)
+}
+
+func TestGearmanSinkBatches(t *testing.T) {
+	c := newMockClient()
+	err := New(c, Config{Function: "function", BatchSize: 2, Concurrency: 3})(slice.New(rows))
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []string{`[{"i":0},{"i":1}]`, `[{"i":2},{"i":3}]`, `{"i":4}`}, c.workloads)
+	assert.LessOrEqual(t, c.most, 3)
+}
+
+func TestGearmanSinkFailures(t *testing.T) {
+	c := newMockClient()
+	input := []optimus.Row{{"i": "fail 0"}, {"i": "ok"}, {"i": "fail 1"}, {"i": "fail 2"}}
+	err := New(c, Config{Function: "function", Concurrency: 2, ErrorLimit: 2})(slice.New(input))
+	assert.Len(t, c.workloads, 4)
+	if assert.IsType(t, &optimus.MultiError{}, err) {
+		errs := err.(*optimus.MultiError)
+		assert.Equal(t, 3, errs.Len())
+		assert.Len(t, errs.Unwrap(), 2)
+		var jobErr *JobError
+		if assert.True(t, errors.As(err, &jobErr)) {
+			assert.Equal(t, "function", jobErr.Function)
+			assert.Len(t, jobErr.Rows, 1)
+			assert.Regexp(t, `^gearman job 'function' failed with warnings: {"i":"fail \d"}$`, jobErr.Error())
+		}
+	}
+
+	// A single failure is returned as is.
+	err = New(newMockClient(), Config{Function: "function"})(slice.New(input[:2]))
+	assert.IsType(t, &JobError{}, err)
+}
+
+func TestGearmanSinkErrors(t *testing.T) {
+	c := &mockClient{Mock: &mock.Mock{}}
+	c.On("Submit", "function", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("no connection"))
+	err := New(c, Config{Function: "function", Encode: func(rows []optimus.Row) ([]byte, error) {
+		if rows[0]["i"] == 1 {
+			return nil, errors.New("can't encode 1")
+		}
+		return nil, nil
+	}})(slice.New(rows[:2]))
+	assert.EqualError(t, err, "2 errors: no connection; can't encode 1")
+
+	err = New(newMockClient(), Config{Function: "function"})(errorSource.New(errors.New("source error")))
+	assert.EqualError(t, err, "source error")
+}
@@ -24,5 +24,5 @@ func TestJSONSink(t *testing.T) {
 func TestJSONSinkError(t *testing.T) {
 	source := errorSource.New(errors.New("failed"))
 	assert.EqualError(t, New(&bytes.Buffer{})(source), "failed")
-	assert.True(t, source.Stopped)
+	assert.True(t, source.WasStopped())
 }
@@ -0,0 +1,47 @@
+package slice
+
+import (
+	"github.com/Clever/optimus/v4"
+)
+
+// New appends all of the Rows in a Table to a slice.
+func New(out *[]optimus.Row) optimus.Sink {
+	return func(source optimus.Table) error {
+		defer source.Stop()
+		for row := range source.Rows() {
+			*out = append(*out, row)
+		}
+		return source.Err()
+	}
+}
+
+// NewStructs decodes all of the Rows in a Table into structs with optimus.DecodeRow, and appends
+// them to a slice. Fields of the Rows that the struct doesn't have are ignored.
+func NewStructs[T any](out *[]T) optimus.Sink {
+	return newStructs(out, optimus.DecodeRow)
+}
+
+// NewStrictStructs is like NewStructs, but uses optimus.DecodeRowStrict, so it's an error if a
+// Row has a field that the struct doesn't.
+func NewStrictStructs[T any](out *[]T) optimus.Sink {
+	return newStructs(out, optimus.DecodeRowStrict)
+}
+
+func newStructs[T any](out *[]T, decode func(optimus.Row, interface{}) error) optimus.Sink {
+	return func(source optimus.Table) error {
+		defer func() {
+			source.Stop()
+			for range source.Rows() {
+				// Drain the Table so that nothing is left blocked sending Rows
+			}
+		}()
+		for row := range source.Rows() {
+			var v T
+			if err := decode(row, &v); err != nil {
+				return err
+			}
+			*out = append(*out, v)
+		}
+		return source.Err()
+	}
+}
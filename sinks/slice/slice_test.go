@@ -0,0 +1,57 @@
+package slice
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	errorSource "github.com/Clever/optimus/v4/sources/error"
+	sliceSource "github.com/Clever/optimus/v4/sources/slice"
+	"github.com/stretchr/testify/assert"
+)
+
+type student struct {
+	ID    string `optimus:"id"`
+	Name  string `optimus:"name"`
+	Grade int    `optimus:"grade"`
+}
+
+func TestNew(t *testing.T) {
+	rows := []optimus.Row{{"id": "1"}, {"id": "2"}}
+	out := []optimus.Row{}
+	assert.Nil(t, New(&out)(sliceSource.New(rows)))
+	assert.Equal(t, rows, out)
+
+	assert.EqualError(t, New(&out)(errorSource.New(fmt.Errorf("garbage error"))), "garbage error")
+}
+
+func TestNewStructs(t *testing.T) {
+	rows := []optimus.Row{
+		{"id": "1", "name": "Ada", "grade": "9", "school": "s1"},
+		{"id": "2", "name": "Grace", "grade": float64(10)},
+	}
+	expected := []student{{ID: "1", Name: "Ada", Grade: 9}, {ID: "2", Name: "Grace", Grade: 10}}
+
+	out := []student{}
+	assert.Nil(t, NewStructs(&out)(sliceSource.New(rows)))
+	assert.Equal(t, expected, out)
+
+	out = []student{}
+	assert.EqualError(t, NewStrictStructs(&out)(sliceSource.New(rows)),
+		"cannot decode 'school' into student, it has no such field")
+	assert.Empty(t, out)
+
+	out = []student{}
+	assert.EqualError(t, NewStructs(&out)(sliceSource.New([]optimus.Row{{"grade": "nine"}, {"grade": 1}})),
+		`cannot decode 'grade' into student.Grade of type int, had value: "nine"`)
+	assert.Empty(t, out)
+}
+
+// TestRoundTrip tests that structs that are sent by the struct source are decoded back into the
+// same structs by the struct sink.
+func TestRoundTrip(t *testing.T) {
+	students := []student{{ID: "1", Name: "Ada", Grade: 9}, {ID: "2", Name: "Grace", Grade: 10}}
+	out := []student{}
+	assert.Nil(t, NewStrictStructs(&out)(sliceSource.NewStructs(students)))
+	assert.Equal(t, students, out)
+}
@@ -4,22 +4,63 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
-	"sync"
+	"strconv"
 
 	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/checkpoint"
+	"github.com/Clever/optimus/v4/sources/internal/source"
 )
 
+// table reads a CSV, and sends its Rows one at a time or in batches.
 type table struct {
-	err     error
-	rows    chan optimus.Row
-	m       sync.Mutex
-	stopped bool
+	source.Sender
 }
 
 func (t *table) start(reader *csv.Reader) {
-	defer t.Stop()
-	defer close(t.rows)
+	defer t.Close()
+	defer t.Recover("sources/csv")
 
+	headers, err := t.readHeaders(reader)
+	if err != nil {
+		return
+	}
+	t.readRows(reader, headers, 0, false)
+}
+
+// startResumable reads the CSV like start, except that it begins after position, and adds the
+// position of each Row to it. The position is the byte offset of the end of the Row's record.
+func (t *table) startResumable(in io.ReadSeeker, position string, newReader func(io.Reader) *csv.Reader) {
+	defer t.Close()
+	defer t.Recover("sources/csv")
+
+	start, err := in.Seek(0, io.SeekCurrent)
+	if err != nil {
+		t.handleErr(err)
+		return
+	}
+	reader := newReader(in)
+	headers, err := t.readHeaders(reader)
+	if err != nil {
+		return
+	}
+	var offset int64
+	if position != "" {
+		offset, err = strconv.ParseInt(position, 10, 64)
+		if err != nil || offset < reader.InputOffset() {
+			t.handleErr(fmt.Errorf("invalid CSV position %q", position))
+			return
+		}
+		// The reader has buffered past the headers, so start a new one at the position.
+		if _, err := in.Seek(start+offset, io.SeekStart); err != nil {
+			t.handleErr(err)
+			return
+		}
+		reader = newReader(in)
+	}
+	t.readRows(reader, headers, offset, true)
+}
+
+func (t *table) readHeaders(reader *csv.Reader) ([]string, error) {
 	headers, err := reader.Read()
 	if err != nil {
 		if perr, ok := err.(*csv.ParseError); ok {
@@ -27,43 +68,34 @@ func (t *table) start(reader *csv.Reader) {
 			perr.Err = fmt.Errorf("%s. %s", perr.Err, "This can happen when the CSV is malformed, or when the wrong delimiter is used")
 		}
 		t.handleErr(err)
-		return
+		return nil, err
 	}
+	return headers, nil
+}
 
+// readRows sends the Rows of the CSV. If resumable is true, it adds the position of each Row,
+// which is offset plus the reader's offset.
+func (t *table) readRows(reader *csv.Reader, headers []string, offset int64, resumable bool) {
 	reader.FieldsPerRecord = len(headers)
 	for {
-		t.m.Lock()
-		stopped := t.stopped
-		t.m.Unlock()
-		if stopped {
-			break
-		}
 		line, err := reader.Read()
 		if err != nil {
 			t.handleErr(err)
 			return
 		}
-		t.rows <- convertLineToRow(line, headers)
+		row := convertLineToRow(line, headers)
+		if resumable {
+			row[checkpoint.PositionField] = strconv.FormatInt(offset+reader.InputOffset(), 10)
+		}
+		if !t.Send(row) {
+			return
+		}
 	}
 }
 
-func (t *table) Rows() <-chan optimus.Row {
-	return t.rows
-}
-
-func (t *table) Err() error {
-	return t.err
-}
-
-func (t *table) Stop() {
-	t.m.Lock()
-	t.stopped = true
-	t.m.Unlock()
-}
-
 func (t *table) handleErr(err error) {
 	if err != io.EOF {
-		t.err = err
+		t.SetErr(err)
 	}
 }
 
@@ -82,9 +114,38 @@ func New(in io.Reader) optimus.Table {
 
 // NewWithCsvReader returns a new Table that scans over the rows from the csv reader.
 func NewWithCsvReader(reader *csv.Reader) optimus.Table {
-	table := &table{
-		rows: make(chan optimus.Row),
-	}
-	go table.start(reader)
-	return table
+	rows := source.New()
+	go (&table{rows}).start(reader)
+	return rows
+}
+
+// NewResumable returns a new Table that scans over the rows of a CSV like New, and adds the
+// position of each Row to its checkpoint.PositionField. If position isn't "", the Table resumes
+// after the Row with that position.
+func NewResumable(in io.ReadSeeker, position string) optimus.Table {
+	return NewResumableWithCsvReader(in, position, func(r io.Reader) *csv.Reader {
+		return csv.NewReader(r)
+	})
+}
+
+// NewResumableWithCsvReader is like NewResumable, but reads the CSV with the csv readers that
+// newReader returns. It may be called more than once, to read from different positions.
+func NewResumableWithCsvReader(in io.ReadSeeker, position string, newReader func(io.Reader) *csv.Reader) optimus.Table {
+	rows := source.New()
+	go (&table{rows}).startResumable(in, position, newReader)
+	return rows
+}
+
+// NewBatched returns a new BatchTable that scans over the rows of a CSV like New, and sends them
+// in batches of size Rows.
+func NewBatched(in io.Reader, size int) optimus.BatchTable {
+	return NewBatchedWithCsvReader(csv.NewReader(in), size)
+}
+
+// NewBatchedWithCsvReader returns a new BatchTable that scans over the rows from the csv reader,
+// and sends them in batches of size Rows.
+func NewBatchedWithCsvReader(reader *csv.Reader, size int) optimus.BatchTable {
+	batches := source.NewBatches(size)
+	go (&table{batches}).start(reader)
+	return batches
 }
@@ -3,9 +3,13 @@ package csv
 import (
 	"bytes"
 	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
 	"testing"
 
 	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/checkpoint"
 	"github.com/Clever/optimus/v4/tests"
 	"github.com/stretchr/testify/assert"
 )
@@ -40,3 +44,209 @@ func TestTabSource(t *testing.T) {
 func TestStop(t *testing.T) {
 	tests.Stop(t, New(bytes.NewBufferString(csvData)))
 }
+
+func TestResumable(t *testing.T) {
+	table := NewResumable(strings.NewReader(csvData), "")
+	rows := tests.GetRows(table)
+	assert.Nil(t, table.Err())
+	// Each position is the offset of the end of its record.
+	assert.Equal(t, []string{"45", "66", "87"}, []string{
+		rows[0][checkpoint.PositionField].(string),
+		rows[1][checkpoint.PositionField].(string),
+		rows[2][checkpoint.PositionField].(string),
+	})
+
+	table = NewResumable(strings.NewReader(csvData), "45")
+	rows = tests.GetRows(table)
+	assert.Nil(t, table.Err())
+	assert.Equal(t, 2, len(rows))
+	delete(rows[0], checkpoint.PositionField)
+	assert.Equal(t, expected[1], rows[0])
+
+	table = NewResumable(strings.NewReader(csvData), "87")
+	tests.Consumed(t, table)
+	assert.Nil(t, table.Err())
+
+	tests.Stop(t, NewResumable(strings.NewReader(csvData), ""))
+
+	table = NewResumable(strings.NewReader(csvData), "3")
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), `invalid CSV position "3"`)
+}
+
+func TestResumableWithCsvReader(t *testing.T) {
+	newReader := func(r io.Reader) *csv.Reader {
+		reader := csv.NewReader(r)
+		reader.Comma = '\t'
+		return reader
+	}
+	table := NewResumableWithCsvReader(strings.NewReader(tabData), "45", newReader)
+	rows := tests.GetRows(table)
+	assert.Nil(t, table.Err())
+	assert.Equal(t, optimus.Row{
+		"header1": "field4", "header2": "field5", "header3": "field6", checkpoint.PositionField: "66",
+	}, rows[0])
+}
+
+// TestResumeAfterFailure tests that a pipeline that fails part of the way through resumes after
+// the last committed Row.
+func TestResumeAfterFailure(t *testing.T) {
+	store := &checkpoint.MemoryStore{}
+	committed := []optimus.Row{}
+	fail := true
+	commit := func(rows []optimus.Row) error {
+		if fail && len(committed) > 0 {
+			return fmt.Errorf("transient error")
+		}
+		committed = append(committed, rows...)
+		return nil
+	}
+	sink := checkpoint.NewSink(checkpoint.Config{Store: store, Size: 2}, commit)
+
+	assert.EqualError(t, sink(NewResumable(strings.NewReader(csvData), "")),
+		"writing a batch of 1 rows failed after 1 attempts: transient error")
+	assert.Equal(t, expected[:2], committed)
+
+	fail = false
+	position, err := store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "66", position)
+	assert.Nil(t, sink(NewResumable(strings.NewReader(csvData), position)))
+	assert.Equal(t, expected, committed)
+}
+
+func TestNewBatched(t *testing.T) {
+	table := NewBatched(bytes.NewBufferString(csvData), 2)
+	var sizes []int
+	rows := []optimus.Row{}
+	for batch := range table.Rows() {
+		sizes = append(sizes, len(batch))
+		rows = append(rows, batch...)
+	}
+	assert.Nil(t, table.Err())
+	assert.Equal(t, []int{2, 1}, sizes)
+	assert.Equal(t, expected, rows)
+
+	// The Rows before a malformed record are sent before the error.
+	table = NewBatched(bytes.NewBufferString(csvData+"field10\n"), 10)
+	assert.Equal(t, expected, tests.GetRows(optimus.Unbatched(table)))
+	assert.EqualError(t, table.Err(), "record on line 5: wrong number of fields")
+}
+
+func TestConformance(t *testing.T) {
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return New(bytes.NewBufferString(csvData))
+	})
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return NewResumable(strings.NewReader(csvData), "")
+	})
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return optimus.Unbatched(NewBatched(bytes.NewBufferString(csvData), 2))
+	})
+}
+
+// fuzzSeeds are inputs that have tripped up CSV parsers before.
+var fuzzSeeds = []string{
+	csvData,
+	tabData,
+	"",
+	"header1\n",
+	"\xef\xbb\xbfheader1,header2\r\nfield1,field2\r\n",
+	"header1,header2\r\n\"multi\r\nline\",\"quoted \"\"field\"\"\"\r\n",
+	"header1,header2\nfield1\n",
+	"header1,header2\n\"unterminated,field2\n",
+	"header1,header1\nfield1,field2\n",
+	"\xff\xfe,\xc3\x28\n\xe2\x82,\x80\n",
+	"header1\n" + strings.Repeat("a", 70000) + "\n",
+	"a,\"b\nc\"d,e\n",
+}
+
+// FuzzNew checks that New reads untrusted input without panicking or leaking goroutines. The leak
+// checks make minimizing big inputs slow, so run it with -fuzzminimizetime, e.g.
+//
+//	go test ./sources/csv -run FuzzNew -fuzz FuzzNew -fuzzminimizetime 1s
+func FuzzNew(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// The Table should read exactly what encoding/csv reads.
+		var expected []optimus.Row
+		reader := csv.NewReader(bytes.NewReader(data))
+		headers, expectedErr := reader.Read()
+		if expectedErr == nil {
+			reader.FieldsPerRecord = len(headers)
+			for {
+				line, err := reader.Read()
+				if err != nil {
+					expectedErr = err
+					break
+				}
+				expected = append(expected, convertLineToRow(line, headers))
+			}
+		}
+
+		tests.NoLeakedGoroutines(t, func() {
+			table := New(bytes.NewReader(data))
+			rows := tests.GetRows(table)
+			if expectedErr == io.EOF {
+				assert.Nil(t, table.Err())
+			} else if assert.Error(t, table.Err()) {
+				// Errors in the headers have a hint added to them.
+				assert.True(t, strings.HasPrefix(table.Err().Error(), expectedErr.Error()), table.Err().Error())
+			}
+			// Err doesn't change once the Table is done.
+			assert.Equal(t, table.Err(), table.Err())
+			if len(expected) == 0 {
+				assert.Empty(t, rows)
+			} else {
+				assert.Equal(t, expected, rows)
+			}
+
+			// Memory is bounded by the input: a Row's values are never more than its record.
+			size := 0
+			for _, row := range rows {
+				for _, value := range row {
+					size += len(value.(string))
+				}
+			}
+			assert.LessOrEqual(t, size, len(data))
+		})
+
+		tests.NoLeakedGoroutines(t, func() {
+			table := New(bytes.NewReader(data))
+			<-table.Rows()
+			table.Stop()
+		})
+	})
+}
+
+// benchmarkCSV returns a CSV with a header and n records.
+func benchmarkCSV(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("header1,header2,header3\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, "field%d,field%d,field%d\n", i, i+1, i+2)
+	}
+	return buf.Bytes()
+}
+
+// BenchmarkNewBatched compares reading Rows one at a time with New to reading them in batches
+// with NewBatched.
+func BenchmarkNewBatched(b *testing.B) {
+	input := benchmarkCSV(10000)
+	b.Run("New", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tests.GetRows(New(bytes.NewReader(input)))
+		}
+	})
+	for _, size := range []int{16, 256} {
+		b.Run(fmt.Sprintf("NewBatched/size=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				table := NewBatched(bytes.NewReader(input), size)
+				for range table.Rows() {
+				}
+			}
+		})
+	}
+}
@@ -1,14 +1,21 @@
 package error
 
 import (
+	"sync"
+
 	"github.com/Clever/optimus/v4"
 )
 
 // ErrorTable implemements an Optimus Table
 // It's purpose is to return a given error
 type ErrorTable struct {
-	rows    chan optimus.Row
-	err     error
+	rows chan optimus.Row
+	err  error
+	m    sync.Mutex
+	// Stopped is true once the ErrorTable has been stopped.
+	//
+	// Deprecated: Stopped can't be read safely while the ErrorTable may be stopped from another
+	// goroutine. Use WasStopped instead.
 	Stopped bool
 }
 
@@ -26,9 +33,18 @@ func (e *ErrorTable) Rows() <-chan optimus.Row {
 // Stop fulfills the requirement for ErrorTable
 // to implement the Stop function of an Optimus Table
 func (e *ErrorTable) Stop() {
+	e.m.Lock()
+	defer e.m.Unlock()
 	e.Stopped = true
 }
 
+// WasStopped returns whether the ErrorTable has been stopped.
+func (e *ErrorTable) WasStopped() bool {
+	e.m.Lock()
+	defer e.m.Unlock()
+	return e.Stopped
+}
+
 // New returns a new Table that returns a given error. Primarily used for testing purposes.
 func New(err error) *ErrorTable {
 	table := &ErrorTable{err: err, rows: make(chan optimus.Row)}
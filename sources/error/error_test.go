@@ -0,0 +1,25 @@
+package error
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorTable(t *testing.T) {
+	table := New(errors.New("failed"))
+	tests.HasRows(t, table, 0)
+	assert.EqualError(t, table.Err(), "failed")
+	assert.False(t, table.WasStopped())
+	table.Stop()
+	assert.True(t, table.WasStopped())
+}
+
+func TestConformance(t *testing.T) {
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return New(errors.New("failed"))
+	})
+}
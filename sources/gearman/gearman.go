@@ -5,30 +5,44 @@ import (
 	"sync"
 
 	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/internal/source"
 	"gopkg.in/Clever/gearman.v1"
 	"gopkg.in/Clever/gearman.v1/job"
 	gearmanUtils "gopkg.in/Clever/gearman.v1/utils"
 )
 
 type table struct {
-	rows    chan optimus.Row
-	err     error
-	m       sync.Mutex
-	stopped bool
+	*source.Table
+
+	// sendM is held while a Row is sent from the client's goroutine, so that Rows isn't closed
+	// during a send. closed is guarded by it.
+	sendM  sync.Mutex
+	closed bool
 }
 
-func (t *table) Rows() <-chan optimus.Row {
-	return t.rows
+// send sends a Row, unless the Table is stopped or closed first, and returns whether it was sent.
+// It blocks until the Row is received, so a slow consumer slows down the job's client too.
+func (t *table) send(row optimus.Row) bool {
+	t.sendM.Lock()
+	defer t.sendM.Unlock()
+	if t.closed {
+		return false
+	}
+	return t.Send(row)
 }
 
-func (t *table) Err() error {
-	return t.err
+// fail fails the Table, and stops it, so that the rest of the job's data is discarded.
+func (t *table) fail(err error) {
+	t.SetErr(err)
+	t.Stop()
 }
 
-func (t *table) Stop() {
-	t.m.Lock()
-	t.stopped = true
-	t.m.Unlock()
+// close closes the Table, once nothing is sending on it.
+func (t *table) close() {
+	t.sendM.Lock()
+	defer t.sendM.Unlock()
+	t.closed = true
+	t.Close()
 }
 
 type getData struct {
@@ -47,37 +61,61 @@ func (rw *getData) Close() error {
 func (t *table) start(client gearman.Client, fn string, workload []byte,
 	convert func([]byte) (optimus.Row, error)) {
 
+	defer t.close()
 	defer t.Stop()
-	defer close(t.rows)
+	defer t.Recover("sources/gearman")
 
 	data := &getData{handler: func(event []byte) {
-		row, err := convert(event)
+		// Once the Table is stopped, the rest of the job's data is discarded.
+		select {
+		case <-t.Stopped():
+			return
+		default:
+		}
+		// The handler runs in the client's goroutine, so its panics have to be recovered here.
+		var row optimus.Row
+		err := optimus.Recover(func() error {
+			var err error
+			row, err = convert(event)
+			return err
+		})
+		if panicErr, ok := err.(*optimus.PanicError); ok {
+			panicErr.Stage = "sources/gearman"
+		}
 		if err != nil {
-			t.err = err
+			t.fail(err)
 			return
 		}
-		t.rows <- row
+		t.send(row)
 	}}
 	warnings := gearmanUtils.NewBuffer()
 	j, err := client.Submit(fn, workload, data, warnings)
 	if err != nil {
-		t.err = err
+		t.SetErr(err)
 		return
 	}
-	state := j.Run()
-
-	if state == job.Failed {
-		t.err = fmt.Errorf("gearman job '%s' failed with warnings: %s", fn, warnings.Bytes())
+	// Gearman can't cancel a job, so a stopped Table abandons it instead of waiting for it.
+	state := make(chan job.State, 1)
+	go func() {
+		state <- j.Run()
+	}()
+	select {
+	case s := <-state:
+		if s == job.Failed {
+			t.SetErr(fmt.Errorf("gearman job '%s' failed with warnings: %s", fn, warnings.Bytes()))
+		}
+	case <-t.Stopped():
 	}
 }
 
 // New returns a new Table that outputs the worker data from a Gearman job. Converter should be a
-// function that knows how to take a data event from Gearman and turn it into a Row.
+// function that knows how to take a data event from Gearman and turn it into a Row. If it returns
+// an error, the Table fails and stops. Gearman can't cancel a job once it's submitted, so once the
+// Table is stopped, it abandons the job: the Table is closed without waiting for the job to finish,
+// and the rest of the job's data is discarded.
 func New(client gearman.Client, fn string, workload []byte,
 	converter func([]byte) (optimus.Row, error)) optimus.Table {
-	table := &table{
-		rows: make(chan optimus.Row),
-	}
+	table := &table{Table: source.New()}
 	go table.start(client, fn, workload, converter)
 	return table
 }
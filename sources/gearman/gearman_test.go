@@ -104,3 +104,98 @@ func TestGearmanSourceFail(t *testing.T) {
 	assert.Equal(t, expected, tests.GetRows(table))
 	assert.EqualError(t, table.Err(), "gearman job 'function' failed with warnings: 1")
 }
+
+func TestConformance(t *testing.T) {
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		c := &mockClient{Mock: &mock.Mock{}, chans: []chan *packet.Packet{}}
+		c.On("Submit", "function", []byte("workload"), mock.Anything, mock.Anything).Return(nil, nil).Once()
+		table := New(c, "function", []byte("workload"), func(in []byte) (optimus.Row, error) {
+			return optimus.Row{"field1": string(in)}, nil
+		})
+		go func() {
+			for len(getChans(c)) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			packets := getChans(c)[0]
+			for i := 0; i < 3; i++ {
+				packets <- handlePacket("", packet.WorkData, [][]byte{[]byte(fmt.Sprint(i))})
+			}
+			packets <- handlePacket("", packet.WorkComplete, nil)
+			// The client closes a job's packets once it's done, which stops the job's goroutine.
+			close(packets)
+		}()
+		return table
+	})
+}
+
+func TestGearmanSourceConvertError(t *testing.T) {
+	c := &mockClient{Mock: &mock.Mock{}, chans: []chan *packet.Packet{}}
+	c.On("Submit", "function", []byte("workload"), mock.Anything, mock.Anything).Return(nil, nil).Once()
+	table := New(c, "function", []byte("workload"), func(in []byte) (optimus.Row, error) {
+		if string(in) == "bad" {
+			return nil, fmt.Errorf("couldn't convert %s", in)
+		}
+		return optimus.Row{"field1": string(in)}, nil
+	})
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for len(getChans(c)) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		packets := getChans(c)[0]
+		packets <- handlePacket("", packet.WorkData, [][]byte{[]byte("1")})
+		packets <- handlePacket("", packet.WorkData, [][]byte{[]byte("bad")})
+		// The job never completes, and the rest of its data is discarded.
+		packets <- handlePacket("", packet.WorkData, [][]byte{[]byte("2")})
+	}()
+	assert.Equal(t, []optimus.Row{{"field1": "1"}}, tests.GetRows(table))
+	assert.EqualError(t, table.Err(), "couldn't convert bad")
+	<-sent
+}
+
+func TestGearmanSourcePanic(t *testing.T) {
+	c := &mockClient{Mock: &mock.Mock{}, chans: []chan *packet.Packet{}}
+	c.On("Submit", "function", []byte("workload"), mock.Anything, mock.Anything).Return(nil, nil).Once()
+	table := New(c, "function", []byte("workload"), func(in []byte) (optimus.Row, error) {
+		panic("bad data")
+	})
+	go func() {
+		for len(getChans(c)) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		getChans(c)[0] <- handlePacket("", packet.WorkData, [][]byte{[]byte("1")})
+	}()
+	tests.HasRows(t, table, 0)
+	if assert.IsType(t, &optimus.PanicError{}, table.Err()) {
+		assert.Equal(t, "sources/gearman", table.Err().(*optimus.PanicError).Stage)
+	}
+}
+
+// TestGearmanSourceStop tests that a stopped Table abandons a job that doesn't finish.
+func TestGearmanSourceStop(t *testing.T) {
+	c := &mockClient{Mock: &mock.Mock{}, chans: []chan *packet.Packet{}}
+	c.On("Submit", "function", []byte("workload"), mock.Anything, mock.Anything).Return(nil, nil).Once()
+	table := New(c, "function", []byte("workload"), func(in []byte) (optimus.Row, error) {
+		return optimus.Row{"field1": string(in)}, nil
+	})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for len(getChans(c)) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		packets := getChans(c)[0]
+		for i := 0; ; i++ {
+			select {
+			case packets <- handlePacket("", packet.WorkData, [][]byte{[]byte(fmt.Sprint(i))}):
+			case <-done:
+				return
+			}
+		}
+	}()
+	assert.Equal(t, optimus.Row{"field1": "0"}, <-table.Rows())
+	table.Stop()
+	tests.Consumed(t, table)
+	assert.Nil(t, table.Err())
+}
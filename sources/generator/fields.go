@@ -0,0 +1,176 @@
+package generator
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Generator generates the value of a field. It's called with the Table's random source and the
+// index of the Row, starting at 0. It should only use rnd for randomness, so that its values are
+// the same for the same seed.
+type Generator func(rnd *rand.Rand, i int) (interface{}, error)
+
+// Const returns a Generator whose value is always value.
+func Const(value interface{}) Generator {
+	return func(*rand.Rand, int) (interface{}, error) {
+		return value, nil
+	}
+}
+
+// Sequence returns a Generator of the ints start, start+step, start+2*step, etc.
+func Sequence(start, step int) Generator {
+	return func(_ *rand.Rand, i int) (interface{}, error) {
+		return start + i*step, nil
+	}
+}
+
+// Int returns a Generator of random ints between min and max, inclusive.
+func Int(min, max int) Generator {
+	return func(rnd *rand.Rand, _ int) (interface{}, error) {
+		if min > max {
+			return nil, fmt.Errorf("Int's min %d is greater than its max %d", min, max)
+		}
+		return min + int(rnd.Int63n(int64(max)-int64(min)+1)), nil
+	}
+}
+
+// Float returns a Generator of random float64s between min, inclusive, and max, exclusive.
+func Float(min, max float64) Generator {
+	return func(rnd *rand.Rand, _ int) (interface{}, error) {
+		if min > max {
+			return nil, fmt.Errorf("Float's min %v is greater than its max %v", min, max)
+		}
+		return min + rnd.Float64()*(max-min), nil
+	}
+}
+
+// Choice returns a Generator that chooses one of values, each as likely as the others.
+func Choice(values ...interface{}) Generator {
+	return func(rnd *rand.Rand, _ int) (interface{}, error) {
+		if len(values) == 0 {
+			return nil, fmt.Errorf("Choice needs at least one value")
+		}
+		return values[rnd.Intn(len(values))], nil
+	}
+}
+
+// Weighted is a value for WeightedChoice, which is chosen in proportion to its Weight.
+type Weighted struct {
+	Value  interface{}
+	Weight float64
+}
+
+// WeightedChoice returns a Generator that chooses one of the values, in proportion to their
+// weights. E.g. a value with a weight of 3 is chosen three times as often as one with a weight
+// of 1.
+func WeightedChoice(choices ...Weighted) Generator {
+	return func(rnd *rand.Rand, _ int) (interface{}, error) {
+		total := 0.0
+		for _, choice := range choices {
+			if choice.Weight < 0 {
+				return nil, fmt.Errorf("WeightedChoice's weights can't be negative, got %v for %#v",
+					choice.Weight, choice.Value)
+			}
+			total += choice.Weight
+		}
+		if total <= 0 {
+			return nil, fmt.Errorf("WeightedChoice needs a value with a positive weight")
+		}
+		n := rnd.Float64() * total
+		for _, choice := range choices {
+			if n -= choice.Weight; n < 0 {
+				return choice.Value, nil
+			}
+		}
+		// Rounding errors can leave n at about 0, so the last value with a weight is chosen.
+		for i := len(choices) - 1; ; i-- {
+			if choices[i].Weight > 0 {
+				return choices[i].Value, nil
+			}
+		}
+	}
+}
+
+// Date returns a Generator of random time.Times between from, inclusive, and to, exclusive.
+func Date(from, to time.Time) Generator {
+	return func(rnd *rand.Rand, _ int) (interface{}, error) {
+		if !from.Before(to) {
+			return nil, fmt.Errorf("Date's from %s isn't before its to %s", from, to)
+		}
+		return from.Add(time.Duration(rnd.Int63n(int64(to.Sub(from))))), nil
+	}
+}
+
+// Nullable returns a Generator whose value is nil with probability p, and otherwise a value from
+// gen.
+func Nullable(p float64, gen Generator) Generator {
+	return func(rnd *rand.Rand, i int) (interface{}, error) {
+		if p < 0 || p > 1 {
+			return nil, fmt.Errorf("Nullable's probability must be between 0 and 1, got %v", p)
+		}
+		// Always use the random source the same number of times, so that the values of the other
+		// fields don't depend on which values are nil.
+		null := rnd.Float64() < p
+		value, err := gen(rnd, i)
+		if null || err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+}
+
+// FirstName returns a Generator of first names, from a built-in list.
+func FirstName() Generator {
+	return Choice(toValues(firstNames)...)
+}
+
+// LastName returns a Generator of last names, from a built-in list.
+func LastName() Generator {
+	return Choice(toValues(lastNames)...)
+}
+
+// FullName returns a Generator of a first name and a last name, separated by a space.
+func FullName() Generator {
+	return func(rnd *rand.Rand, _ int) (interface{}, error) {
+		return firstNames[rnd.Intn(len(firstNames))] + " " + lastNames[rnd.Intn(len(lastNames))], nil
+	}
+}
+
+// Email returns a Generator of email addresses made from a first name, a last name and the index
+// of the Row, so that they're unique. Their domains are reserved for examples, so they can't
+// belong to anyone.
+func Email() Generator {
+	return func(rnd *rand.Rand, i int) (interface{}, error) {
+		first := firstNames[rnd.Intn(len(firstNames))]
+		last := lastNames[rnd.Intn(len(lastNames))]
+		domain := emailDomains[rnd.Intn(len(emailDomains))]
+		return fmt.Sprintf("%s.%s%d@%s", strings.ToLower(first), strings.ToLower(last), i, domain), nil
+	}
+}
+
+func toValues(words []string) []interface{} {
+	values := make([]interface{}, len(words))
+	for i, word := range words {
+		values[i] = word
+	}
+	return values
+}
+
+var firstNames = []string{
+	"Ada", "Alan", "Amara", "Andre", "Ayesha", "Barbara", "Carlos", "Chen", "Dana", "Diego",
+	"Edsger", "Elena", "Fatima", "Frances", "Grace", "Hedy", "Hiro", "Ines", "Jamal", "Jean",
+	"Joan", "Kenji", "Katherine", "Leila", "Linus", "Maria", "Mateo", "Mei", "Noor", "Omar",
+	"Priya", "Radia", "Rosa", "Sofia", "Tariq", "Tim", "Valentina", "Wei", "Yusuf", "Zoe",
+}
+
+var lastNames = []string{
+	"Abara", "Borg", "Castillo", "Dijkstra", "Easley", "Fernandez", "Goldberg", "Hamilton",
+	"Hopper", "Ibrahim", "Johnson", "Kahn", "Kowalski", "Lamarr", "Lovelace", "Martinez",
+	"Nakamura", "Nguyen", "Okafor", "Patel", "Perlman", "Quispe", "Ritchie", "Rossi", "Sato",
+	"Shaw", "Silva", "Thompson", "Turing", "Ueda", "Vasquez", "Wang", "Williams", "Xu", "Yilmaz",
+	"Zhang",
+}
+
+var emailDomains = []string{"example.com", "example.org", "example.net"}
@@ -0,0 +1,105 @@
+/*
+Package generator is a source of synthetic Rows, for load testing pipelines and for producing
+fixtures that don't contain real data.
+
+Each field of the Rows is made by a Generator:
+
+	table := generator.New(generator.Config{
+		Fields: map[string]generator.Generator{
+			"id":     generator.Sequence(1, 1),
+			"name":   generator.FullName(),
+			"email":  generator.Email(),
+			"grade":  generator.Int(1, 12),
+			"gpa":    generator.Nullable(0.1, generator.Float(0, 4)),
+			"school": generator.WeightedChoice(
+				generator.Weighted{Value: "Hogwarts", Weight: 3},
+				generator.Weighted{Value: "Xavier's", Weight: 1},
+			),
+		},
+		Seed: 42,
+		Rows: 1000,
+	})
+
+The same Config always generates the same Rows.
+*/
+package generator
+
+import (
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/internal/source"
+)
+
+// Config configures the Rows that a generator Table sends.
+type Config struct {
+	// Fields maps the name of each field to the Generator of its values.
+	Fields map[string]Generator
+	// Seed seeds the random values, so that the same Config always generates the same Rows.
+	Seed int64
+	// Rows is how many Rows to send. If it's 0, Rows are sent until the Table is stopped.
+	Rows int
+	// Rate is how many Rows to send a second. If it's 0, Rows are sent as fast as they're read.
+	Rate float64
+}
+
+type table struct {
+	*source.Table
+}
+
+// wait waits until it's time to send a Row, and returns false if the Table is stopped first.
+func (t *table) wait(until time.Time) bool {
+	d := time.Until(until)
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-t.Stopped():
+		return false
+	}
+}
+
+func (t *table) start(config Config) {
+	defer t.Close()
+	defer t.Recover("sources/generator")
+
+	// Generate the fields in order, so that they get the same random values every time.
+	fields := make([]string, 0, len(config.Fields))
+	for field := range config.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	rnd := rand.New(rand.NewSource(config.Seed))
+	begin := time.Now()
+	for i := 0; config.Rows <= 0 || i < config.Rows; i++ {
+		row := optimus.Row{}
+		for _, field := range fields {
+			value, err := config.Fields[field](rnd, i)
+			if err != nil {
+				t.SetErr(err)
+				return
+			}
+			row[field] = value
+		}
+		if config.Rate > 0 && !t.wait(begin.Add(time.Duration(float64(i)/config.Rate*float64(time.Second)))) {
+			return
+		}
+		if !t.Send(row) {
+			return
+		}
+	}
+}
+
+// New returns a new Table that sends Rows generated as described by the Config.
+func New(config Config) optimus.Table {
+	table := &table{source.New()}
+	go table.start(config)
+	return table
+}
@@ -0,0 +1,123 @@
+package generator
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+var from = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func students(seed int64, rows int) Config {
+	return Config{
+		Fields: map[string]Generator{
+			"id":       Sequence(1, 1),
+			"name":     FullName(),
+			"email":    Email(),
+			"grade":    Int(9, 12),
+			"gpa":      Nullable(0.2, Float(0, 4)),
+			"school":   WeightedChoice(Weighted{"a", 3}, Weighted{"b", 1}, Weighted{"never", 0}),
+			"enrolled": Date(from, from.AddDate(1, 0, 0)),
+			"district": Const("d1"),
+		},
+		Seed: seed,
+		Rows: rows,
+	}
+}
+
+func TestNew(t *testing.T) {
+	rows := tests.GetRows(New(students(42, 1000)))
+	assert.Len(t, rows, 1000)
+	nulls, schools := 0, map[interface{}]int{}
+	for i, row := range rows {
+		assert.Len(t, row, 8)
+		assert.Equal(t, i+1, row["id"])
+		assert.Equal(t, "d1", row["district"])
+		assert.Regexp(t, `^[A-Z][a-z]+ [A-Z][a-z]+$`, row["name"])
+		assert.Regexp(t, `^[a-z]+\.[a-z]+\d+@example\.(com|org|net)$`, row["email"])
+		grade := row["grade"].(int)
+		assert.True(t, grade >= 9 && grade <= 12, "grade %d", grade)
+		if row["gpa"] == nil {
+			nulls++
+		} else {
+			gpa := row["gpa"].(float64)
+			assert.True(t, gpa >= 0 && gpa < 4, "gpa %v", gpa)
+		}
+		enrolled := row["enrolled"].(time.Time)
+		assert.False(t, enrolled.Before(from) || !enrolled.Before(from.AddDate(1, 0, 0)), "enrolled %s", enrolled)
+		schools[row["school"]]++
+	}
+	assert.InDelta(t, 200, nulls, 50)
+	assert.InDelta(t, 750, schools["a"], 60)
+	assert.InDelta(t, 250, schools["b"], 60)
+	assert.Zero(t, schools["never"])
+}
+
+func TestDeterministic(t *testing.T) {
+	first := tests.GetRows(New(students(7, 50)))
+	assert.Equal(t, first, tests.GetRows(New(students(7, 50))))
+	assert.NotEqual(t, first, tests.GetRows(New(students(8, 50))))
+}
+
+func TestRate(t *testing.T) {
+	start := time.Now()
+	table := New(Config{Fields: map[string]Generator{"id": Sequence(0, 1)}, Rows: 6, Rate: 100})
+	assert.Len(t, tests.GetRows(table), 6)
+	// The first Row is sent right away, and the rest every 10ms.
+	assert.True(t, time.Since(start) >= 50*time.Millisecond, "took %s", time.Since(start))
+
+	// Stopping doesn't wait for the next Row.
+	table = New(Config{Fields: map[string]Generator{"id": Sequence(0, 1)}, Rate: 0.001})
+	<-table.Rows()
+	tests.Stop(t, table)
+}
+
+func TestErrors(t *testing.T) {
+	for expected, gen := range map[string]Generator{
+		"Int's min 2 is greater than its max 1":                                                       Int(2, 1),
+		"Float's min 2 is greater than its max 1":                                                     Float(2, 1),
+		"Choice needs at least one value":                                                             Choice(),
+		"WeightedChoice needs a value with a positive weight":                                         WeightedChoice(Weighted{"a", 0}),
+		"WeightedChoice's weights can't be negative, got -1 for \"a\"":                                WeightedChoice(Weighted{"a", -1}),
+		"Nullable's probability must be between 0 and 1, got 2":                                       Nullable(2, Const(1)),
+		"Date's from 2024-01-01 00:00:00 +0000 UTC isn't before its to 2024-01-01 00:00:00 +0000 UTC": Date(from, from),
+	} {
+		_, err := gen(rand.New(rand.NewSource(0)), 0)
+		assert.EqualError(t, err, expected)
+	}
+	table := New(Config{Fields: map[string]Generator{"grade": Int(2, 1)}})
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "Int's min 2 is greater than its max 1")
+}
+
+func TestStop(t *testing.T) {
+	tests.Stop(t, New(students(1, 0)))
+}
+
+func TestConformance(t *testing.T) {
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return New(students(1, 10))
+	})
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return New(students(1, 0))
+	})
+}
+
+func TestPanic(t *testing.T) {
+	table := New(Config{Fields: map[string]Generator{
+		"id": func(_ *rand.Rand, i int) (interface{}, error) {
+			if i == 2 {
+				panic("bad generator")
+			}
+			return i, nil
+		},
+	}, Rows: 5})
+	tests.HasRows(t, table, 2)
+	if assert.IsType(t, &optimus.PanicError{}, table.Err()) {
+		assert.Equal(t, "sources/generator", table.Err().(*optimus.PanicError).Stage)
+	}
+}
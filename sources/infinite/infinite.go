@@ -1,48 +1,26 @@
 package infinite
 
 import (
-	"sync"
-
 	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/internal/source"
 )
 
 type infiniteTable struct {
-	rows    chan optimus.Row
-	m       sync.Mutex
-	stopped bool
-}
-
-func (i *infiniteTable) Rows() <-chan optimus.Row {
-	return i.rows
-}
-
-func (i *infiniteTable) Err() error {
-	return nil
-}
-
-func (i *infiniteTable) Stop() {
-	i.m.Lock()
-	i.stopped = true
-	i.m.Unlock()
+	*source.Table
 }
 
 func (i *infiniteTable) start() {
-	defer i.Stop()
-	defer close(i.rows)
+	defer i.Close()
 	for {
-		i.m.Lock()
-		stopped := i.stopped
-		i.m.Unlock()
-		if stopped {
-			break
+		if !i.Send(optimus.Row{}) {
+			return
 		}
-		i.rows <- map[string]interface{}{}
 	}
 }
 
 // New creates a new Table that infinitely sends empty rows.
 func New() optimus.Table {
-	table := &infiniteTable{rows: make(chan optimus.Row)}
+	table := &infiniteTable{source.New()}
 	go table.start()
 	return table
 }
@@ -3,9 +3,16 @@ package infinite
 import (
 	"testing"
 
+	"github.com/Clever/optimus/v4"
 	"github.com/Clever/optimus/v4/tests"
 )
 
 func TestStop(t *testing.T) {
 	tests.Stop(t, New())
 }
+
+func TestConformance(t *testing.T) {
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return New()
+	})
+}
@@ -0,0 +1,174 @@
+// Package source has the plumbing that sources share to send Rows, fail, and be stopped.
+package source
+
+import (
+	"sync"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// Sender is what a source sends its Rows with, one at a time with a Table, or in batches with
+// Batches, so that the same source can make both kinds of Table.
+type Sender interface {
+	// Send sends a Row, unless the Table is stopped first, and returns whether it was sent.
+	Send(row optimus.Row) bool
+	// SetErr sets the Table's error, unless err is nil or the Table already failed.
+	SetErr(err error)
+	// Stopped returns a channel that's closed once the Table is stopped.
+	Stopped() <-chan struct{}
+	// Recover fails the Table with an *optimus.PanicError from stage if its goroutine panics.
+	Recover(stage string)
+	// Close closes the Table's channel and stops it. It must only be called once, after the last
+	// Send.
+	Close()
+}
+
+// stopper has the error and stop state that Tables and Batches share.
+type stopper struct {
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	m        sync.Mutex
+	err      error
+}
+
+// Err implements the optimus.Table interface.
+func (s *stopper) Err() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.err
+}
+
+// Stop implements the optimus.Table interface. It can be called any number of times, from any
+// goroutine.
+func (s *stopper) Stop() {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+}
+
+// Stopped returns a channel that's closed once the Table is stopped.
+func (s *stopper) Stopped() <-chan struct{} {
+	return s.stopCh
+}
+
+// SetErr sets the Table's error, unless err is nil or the Table already failed.
+func (s *stopper) SetErr(err error) {
+	if err == nil {
+		return
+	}
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Recover fails the Table with an *optimus.PanicError from stage if its goroutine panics. Sources
+// defer it in the goroutine that sends their Rows.
+func (s *stopper) Recover(stage string) {
+	if r := recover(); r != nil {
+		err := optimus.NewPanicError(r)
+		err.Stage = stage
+		s.SetErr(err)
+	}
+}
+
+// send sends a value, unless stopCh is closed first, and returns whether it was sent.
+func send[T any](stopCh <-chan struct{}, c chan<- T, value T) bool {
+	// Check first, so that a value that's ready to be read isn't sent after Stop.
+	select {
+	case <-stopCh:
+		return false
+	default:
+	}
+	select {
+	case c <- value:
+		return true
+	case <-stopCh:
+		return false
+	}
+}
+
+// Table implements the Rows, Err and Stop methods of an optimus.Table for a source that sends
+// its Rows from its own goroutine. Sources embed it, send their Rows with Send, and Close it once
+// they're done.
+type Table struct {
+	stopper
+	rows chan optimus.Row
+}
+
+// New returns a new Table.
+func New() *Table {
+	return &Table{stopper: stopper{stopCh: make(chan struct{})}, rows: make(chan optimus.Row)}
+}
+
+// Rows implements the optimus.Table interface.
+func (t *Table) Rows() <-chan optimus.Row {
+	return t.rows
+}
+
+// Send sends a Row, unless the Table is stopped first, and returns whether it was sent.
+func (t *Table) Send(row optimus.Row) bool {
+	return send(t.stopCh, t.rows, row)
+}
+
+// Close closes the Table's Rows and stops it. It must only be called once, after the last Send.
+func (t *Table) Close() {
+	close(t.rows)
+	t.Stop()
+}
+
+// Batches is like Table, but it's an optimus.BatchTable: it builds batches of the Rows it's sent,
+// and only makes a channel send once a batch is full.
+type Batches struct {
+	stopper
+	batches chan []optimus.Row
+	size    int
+	batch   []optimus.Row
+}
+
+// NewBatches returns new Batches of size Rows each. A size less than one means one.
+func NewBatches(size int) *Batches {
+	size = max(size, 1)
+	return &Batches{
+		stopper: stopper{stopCh: make(chan struct{})},
+		batches: make(chan []optimus.Row),
+		size:    size,
+		batch:   make([]optimus.Row, 0, size),
+	}
+}
+
+// Rows implements the optimus.BatchTable interface.
+func (b *Batches) Rows() <-chan []optimus.Row {
+	return b.batches
+}
+
+// Send adds a Row to the next batch, and sends the batch once it's full. It returns false if the
+// Table is stopped first.
+func (b *Batches) Send(row optimus.Row) bool {
+	if b.batch = append(b.batch, row); len(b.batch) < b.size {
+		select {
+		case <-b.stopCh:
+			return false
+		default:
+			return true
+		}
+	}
+	return b.flush()
+}
+
+// flush sends the next batch, if it has any Rows, and returns whether it was sent.
+func (b *Batches) flush() bool {
+	if len(b.batch) == 0 {
+		return true
+	}
+	batch := b.batch
+	b.batch = make([]optimus.Row, 0, b.size)
+	return send(b.stopCh, b.batches, batch)
+}
+
+// Close sends the last batch, even if it isn't full, closes the Batches' channel and stops them.
+// It must only be called once, after the last Send.
+func (b *Batches) Close() {
+	b.flush()
+	close(b.batches)
+	b.Stop()
+}
@@ -0,0 +1,96 @@
+package source
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendAndClose(t *testing.T) {
+	table := New()
+	go func() {
+		defer table.Close()
+		for i := 0; i < 3; i++ {
+			table.Send(optimus.Row{"i": i})
+		}
+		table.SetErr(errors.New("first error"))
+		table.SetErr(errors.New("second error"))
+	}()
+	assert.Equal(t, []optimus.Row{{"i": 0}, {"i": 1}, {"i": 2}}, tests.GetRows(table))
+	assert.EqualError(t, table.Err(), "first error")
+}
+
+func TestSendAfterStop(t *testing.T) {
+	table := New()
+	table.Stop()
+	table.Stop()
+	assert.False(t, table.Send(optimus.Row{}))
+	<-table.Stopped()
+}
+
+func TestRecover(t *testing.T) {
+	table := New()
+	go func() {
+		defer table.Close()
+		defer table.Recover("sources/test")
+		panic("oops")
+	}()
+	tests.Consumed(t, table)
+	var panicErr *optimus.PanicError
+	assert.True(t, errors.As(table.Err(), &panicErr))
+	assert.Equal(t, "sources/test", panicErr.Stage)
+	assert.Equal(t, "oops", panicErr.Value)
+}
+
+func TestConformance(t *testing.T) {
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		table := New()
+		go func() {
+			defer table.Close()
+			for i := 0; i < 10; i++ {
+				if !table.Send(optimus.Row{"i": i}) {
+					return
+				}
+			}
+		}()
+		return table
+	})
+}
+
+func TestBatches(t *testing.T) {
+	batches := NewBatches(2)
+	go func() {
+		defer batches.Close()
+		for i := 0; i < 5; i++ {
+			batches.Send(optimus.Row{"i": i})
+		}
+	}()
+	var sizes []int
+	for batch := range batches.Rows() {
+		sizes = append(sizes, len(batch))
+	}
+	assert.Equal(t, []int{2, 2, 1}, sizes)
+	assert.Nil(t, batches.Err())
+
+	batches = NewBatches(2)
+	batches.Stop()
+	assert.False(t, batches.Send(optimus.Row{}))
+}
+
+func TestBatchesConformance(t *testing.T) {
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		batches := NewBatches(3)
+		go func() {
+			defer batches.Close()
+			for i := 0; i < 10; i++ {
+				if !batches.Send(optimus.Row{"i": i}) {
+					return
+				}
+			}
+		}()
+		return optimus.Unbatched(batches)
+	})
+}
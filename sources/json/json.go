@@ -2,70 +2,88 @@ package json
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
-	"sync"
+	"strconv"
 
 	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/checkpoint"
 	"github.com/Clever/optimus/v4/scanner"
+	"github.com/Clever/optimus/v4/sources/internal/source"
 )
 
+// table reads newline-separated JSON, and sends its Rows one at a time or in batches.
 type table struct {
-	err     error
-	rows    chan optimus.Row
-	m       sync.Mutex
-	stopped bool
-}
-
-func (t *table) Rows() <-chan optimus.Row {
-	return t.rows
-}
-
-func (t *table) Err() error {
-	return t.err
-}
-
-func (t *table) Stop() {
-	t.m.Lock()
-	t.stopped = true
-	t.m.Unlock()
+	source.Sender
 }
 
 func (t *table) handleErr(err error) {
 	if err != io.EOF {
-		t.err = err
+		t.SetErr(err)
 	}
 }
 
-func (t *table) start(in io.Reader) {
-	defer t.Stop()
-	defer close(t.rows)
+// start sends a Row for each line of in. If resumable is true, it adds the line number of each Row
+// as its position, and skips the lines up to and including position.
+func (t *table) start(in io.Reader, resumable bool, position string) {
+	defer t.Close()
+	defer t.Recover("sources/json")
+
+	skip := 0
+	if position != "" {
+		var err error
+		if skip, err = strconv.Atoi(position); err != nil || skip < 0 {
+			t.handleErr(fmt.Errorf("invalid JSON position %q", position))
+			return
+		}
+	}
 
 	scanner := scanner.NewScanner(in)
+	line := 0
 	for scanner.Scan() {
-		t.m.Lock()
-		stopped := t.stopped
-		t.m.Unlock()
-		if stopped {
-			break
+		if line++; line <= skip {
+			continue
 		}
 		var row optimus.Row
 		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
-			t.err = err
+			t.handleErr(err)
+			return
+		}
+		if resumable {
+			if row == nil {
+				row = optimus.Row{}
+			}
+			row[checkpoint.PositionField] = strconv.Itoa(line)
+		}
+		if !t.Send(row) {
 			return
 		}
-		t.rows <- row
 	}
 	if scanner.Err() != nil {
-		t.err = scanner.Err()
-		return
+		t.handleErr(scanner.Err())
 	}
 }
 
 // New returns a new Table that scans over the rows of a file of newline-separate JSON objects.
 func New(in io.Reader) optimus.Table {
-	table := &table{
-		rows: make(chan optimus.Row),
-	}
-	go table.start(in)
-	return table
+	rows := source.New()
+	go (&table{rows}).start(in, false, "")
+	return rows
+}
+
+// NewResumable returns a new Table that scans over the rows of a file of newline-separate JSON
+// objects like New, and adds the position of each Row to its checkpoint.PositionField. If position
+// isn't "", the Table resumes after the Row with that position.
+func NewResumable(in io.Reader, position string) optimus.Table {
+	rows := source.New()
+	go (&table{rows}).start(in, true, position)
+	return rows
+}
+
+// NewBatched returns a new BatchTable that scans over the rows of a file of newline-separate JSON
+// objects like New, and sends them in batches of size Rows.
+func NewBatched(in io.Reader, size int) optimus.BatchTable {
+	batches := source.NewBatches(size)
+	go (&table{batches}).start(in, false, "")
+	return batches
 }
@@ -1,10 +1,16 @@
 package json
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/checkpoint"
+	"github.com/Clever/optimus/v4/scanner"
 	"github.com/Clever/optimus/v4/tests"
 	"github.com/stretchr/testify/assert"
 )
@@ -28,3 +34,156 @@ func TestJSONSource(t *testing.T) {
 func TestStop(t *testing.T) {
 	tests.Stop(t, New(bytes.NewBufferString(jsonData)))
 }
+
+func TestResumable(t *testing.T) {
+	table := NewResumable(bytes.NewBufferString(jsonData), "")
+	rows := tests.GetRows(table)
+	assert.Nil(t, table.Err())
+	assert.Equal(t, 3, len(rows))
+	assert.Equal(t, "3", rows[2][checkpoint.PositionField])
+
+	table = NewResumable(bytes.NewBufferString(jsonData), "2")
+	assert.Equal(t, []optimus.Row{
+		{"header1": "field7", "header2": "field8", "header3": "field9", checkpoint.PositionField: "3"},
+	}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+
+	tests.Stop(t, NewResumable(bytes.NewBufferString(jsonData), "1"))
+
+	table = NewResumable(bytes.NewBufferString(jsonData), "-1")
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), `invalid JSON position "-1"`)
+}
+
+func TestNewBatched(t *testing.T) {
+	table := NewBatched(bytes.NewBufferString(jsonData), 2)
+	var sizes []int
+	rows := []optimus.Row{}
+	for batch := range table.Rows() {
+		sizes = append(sizes, len(batch))
+		rows = append(rows, batch...)
+	}
+	assert.Nil(t, table.Err())
+	assert.Equal(t, []int{2, 1}, sizes)
+	assert.Equal(t, tests.GetRows(New(bytes.NewBufferString(jsonData))), rows)
+
+	// The Rows before invalid JSON are sent before the error.
+	table = NewBatched(bytes.NewBufferString(jsonData+"{\n"), 10)
+	assert.Len(t, tests.GetRows(optimus.Unbatched(table)), 3)
+	assert.EqualError(t, table.Err(), "unexpected end of JSON input")
+}
+
+func TestConformance(t *testing.T) {
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return New(bytes.NewBufferString(jsonData))
+	})
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return optimus.Unbatched(NewBatched(bytes.NewBufferString(jsonData), 2))
+	})
+}
+
+// fuzzSeeds are inputs that have tripped up JSON parsers before.
+var fuzzSeeds = []string{
+	jsonData,
+	"",
+	"\n",
+	"null\n",
+	"[1, 2]\n",
+	"\xef\xbb\xbf{\"a\":1}\n",
+	"{\"a\":1}\r\n{\"b\":2}\r\n",
+	"{\"a\":\"\xff\xfe\"}\n",
+	"{\"a\":\"\\ud800\"}\n",
+	"{\"a\":1e400}\n",
+	"{\"a\":{\"b\":[{\"c\":null}]}, \"a\":2}\n",
+	"{\"a\":1}{\"b\":2}\n",
+	"{\"a\":\"" + strings.Repeat("a", 70000) + "\"}\n",
+	"{\"a\":1}",
+}
+
+// FuzzNew checks that New reads untrusted input without panicking or leaking goroutines. The leak
+// checks make minimizing big inputs slow, so run it with -fuzzminimizetime, e.g.
+//
+//	go test ./sources/json -run FuzzNew -fuzz FuzzNew -fuzzminimizetime 1s
+func FuzzNew(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// The Table should read exactly what encoding/json reads from each line.
+		var expected []optimus.Row
+		var expectedErr error
+		lines := bufio.NewScanner(bytes.NewReader(data))
+		lines.Buffer(nil, scanner.MaxScanTokenSize)
+		for lines.Scan() {
+			var row optimus.Row
+			if expectedErr = json.Unmarshal(lines.Bytes(), &row); expectedErr != nil {
+				break
+			}
+			expected = append(expected, row)
+		}
+		if expectedErr == nil {
+			expectedErr = lines.Err()
+		}
+
+		tests.NoLeakedGoroutines(t, func() {
+			table := New(bytes.NewReader(data))
+			rows := tests.GetRows(table)
+			if expectedErr == nil {
+				assert.Nil(t, table.Err())
+			} else {
+				assert.EqualError(t, table.Err(), expectedErr.Error())
+			}
+			// Err doesn't change once the Table is done.
+			assert.Equal(t, table.Err(), table.Err())
+			if len(expected) == 0 {
+				assert.Empty(t, rows)
+			} else {
+				assert.Equal(t, expected, rows)
+			}
+
+			// Memory is bounded by the input. Re-encoding can escape each byte as \\u00XX at most.
+			size := 0
+			for _, row := range rows {
+				encoded, err := json.Marshal(row)
+				assert.Nil(t, err)
+				size += len(encoded)
+			}
+			assert.LessOrEqual(t, size, 6*len(data))
+		})
+
+		tests.NoLeakedGoroutines(t, func() {
+			table := New(bytes.NewReader(data))
+			<-table.Rows()
+			table.Stop()
+		})
+	})
+}
+
+// benchmarkJSON returns n lines of JSON objects.
+func benchmarkJSON(n int) []byte {
+	var buf bytes.Buffer
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&buf, `{"header1":"field%d","header2":"field%d","header3":"field%d"}`+"\n", i, i+1, i+2)
+	}
+	return buf.Bytes()
+}
+
+// BenchmarkNewBatched compares reading Rows one at a time with New to reading them in batches
+// with NewBatched.
+func BenchmarkNewBatched(b *testing.B) {
+	input := benchmarkJSON(10000)
+	b.Run("New", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tests.GetRows(New(bytes.NewReader(input)))
+		}
+	})
+	for _, size := range []int{16, 256} {
+		b.Run(fmt.Sprintf("NewBatched/size=%d", size), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				table := NewBatched(bytes.NewReader(input), size)
+				for range table.Rows() {
+				}
+			}
+		})
+	}
+}
@@ -11,7 +11,12 @@ package mongo
 */
 
 import (
+	"encoding/json"
+	"fmt"
+
 	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/checkpoint"
+	"github.com/Clever/optimus/v4/sources/internal/source"
 )
 
 // Iter simulates the gopkg.in/mgo.v2.Iter interface so we can remain independent
@@ -22,43 +27,67 @@ type Iter interface {
 
 // New returns a new Table that iterates over all the results of a mongo query.
 func New(iter Iter) optimus.Table {
-	s := &mongoSource{rows: make(chan optimus.Row)}
+	s := &mongoSource{Table: source.New()}
+	go s.start(iter)
+	return s
+}
+
+// NewResumable returns a new Table like New, that adds the _id of each document, encoded as JSON,
+// to its checkpoint.PositionField. The query must be sorted by _id. To resume it, decode the
+// position with DecodePosition and only query for documents with a greater _id, e.g.
+//
+//	var lastID bson.ObjectId
+//	query := bson.M{}
+//	if position != "" {
+//		if err := mongo.DecodePosition(position, &lastID); err != nil {
+//			return err
+//		}
+//		query["_id"] = bson.M{"$gt": lastID}
+//	}
+//	table := mongo.NewResumable(collection.Find(query).Sort("_id").Iter())
+func NewResumable(iter Iter) optimus.Table {
+	s := &mongoSource{Table: source.New(), resumable: true}
 	go s.start(iter)
 	return s
 }
 
+// DecodePosition decodes a position from a Table returned by NewResumable into the _id that id
+// points to.
+func DecodePosition(position string, id interface{}) error {
+	return json.Unmarshal([]byte(position), id)
+}
+
 // mongoSource type matches the gopkg.in/mgo.v2.Iter interface
 type mongoSource struct {
-	err     error
-	rows    chan optimus.Row
-	stopped bool
+	*source.Table
+	resumable bool
 }
 
 // start begins feeding rows into the rows channel
 func (s *mongoSource) start(iter Iter) {
-	defer s.Stop()
-	defer close(s.rows)
-	for !s.stopped {
+	defer s.Close()
+	defer s.Recover("sources/mongo")
+	for {
 		r := optimus.Row{}
 		if !iter.Next(&r) {
 			break
 		}
-		s.rows <- r
+		if s.resumable {
+			id, ok := r["_id"]
+			if !ok {
+				s.SetErr(fmt.Errorf("document has no _id to resume from: %#v", r))
+				return
+			}
+			position, err := json.Marshal(id)
+			if err != nil {
+				s.SetErr(err)
+				return
+			}
+			r[checkpoint.PositionField] = string(position)
+		}
+		if !s.Send(r) {
+			return
+		}
 	}
-	s.err = iter.Err()
-}
-
-// Rows implements the optimus.Table interface
-func (s *mongoSource) Rows() <-chan optimus.Row {
-	return s.rows
-}
-
-// Err implements the optimus.Table interface
-func (s *mongoSource) Err() error {
-	return s.err
-}
-
-// Stop implements the optimus.Table interface
-func (s *mongoSource) Stop() {
-	s.stopped = true
+	s.SetErr(iter.Err())
 }
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/checkpoint"
 	"github.com/Clever/optimus/v4/tests"
 	"github.com/stretchr/testify/assert"
 )
@@ -102,3 +103,31 @@ func TestSuccessRows(t *testing.T) {
 		assert.Equal(t, data.ExpectedErr, sourceTable.Err())
 	}
 }
+
+func TestResumable(t *testing.T) {
+	table := NewResumable(&mongoIter{[]interface{}{
+		map[string]interface{}{"_id": "5f1a", "field1": "field1_data"},
+		map[string]interface{}{"_id": 12, "field2": "field2_data"},
+	}})
+	assert.Equal(t, []optimus.Row{
+		{"_id": "5f1a", "field1": "field1_data", checkpoint.PositionField: `"5f1a"`},
+		{"_id": 12, "field2": "field2_data", checkpoint.PositionField: "12"},
+	}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+
+	var id string
+	assert.Nil(t, DecodePosition(`"5f1a"`, &id))
+	assert.Equal(t, "5f1a", id)
+
+	table = NewResumable(&mongoIter{[]interface{}{map[string]interface{}{"field1": "field1_data"}}})
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), `document has no _id to resume from: optimus.Row{"field1":"field1_data"}`)
+}
+
+func TestConformance(t *testing.T) {
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return NewResumable(&mongoIter{docs: []interface{}{
+			optimus.Row{"_id": 1}, optimus.Row{"_id": 2}, optimus.Row{"_id": 3},
+		}})
+	})
+}
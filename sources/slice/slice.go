@@ -1,48 +1,26 @@
 package slice
 
 import (
-	"sync"
-
 	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/internal/source"
 )
 
 type sliceTable struct {
-	rows    chan optimus.Row
-	m       sync.Mutex
-	stopped bool
-}
-
-func (s *sliceTable) Rows() <-chan optimus.Row {
-	return s.rows
-}
-
-func (s *sliceTable) Err() error {
-	return nil
-}
-
-func (s *sliceTable) Stop() {
-	s.m.Lock()
-	s.stopped = true
-	s.m.Unlock()
+	*source.Table
 }
 
 func (s *sliceTable) start(slice []optimus.Row) {
-	defer s.Stop()
-	defer close(s.rows)
+	defer s.Close()
 	for _, row := range slice {
-		s.m.Lock()
-		stopped := s.stopped
-		s.m.Unlock()
-		if stopped {
-			break
+		if !s.Send(row) {
+			return
 		}
-		s.rows <- row
 	}
 }
 
 // New creates a new Table that sends all the contents of an input slice of Rows.
 func New(slice []optimus.Row) optimus.Table {
-	table := &sliceTable{rows: make(chan optimus.Row)}
+	table := &sliceTable{source.New()}
 	go table.start(slice)
 	return table
 }
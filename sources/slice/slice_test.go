@@ -5,6 +5,7 @@ import (
 
 	"github.com/Clever/optimus/v4"
 	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestStop(t *testing.T) {
@@ -13,3 +14,53 @@ func TestStop(t *testing.T) {
 		{"thing2": []string{"1", "2"}},
 	}))
 }
+
+type student struct {
+	Name  string `optimus:"name"`
+	Grade int    `optimus:"grade,omitempty"`
+}
+
+func TestNewStructs(t *testing.T) {
+	table := NewStructs([]student{{Name: "Ada", Grade: 9}, {Name: "Grace"}})
+	assert.Equal(t, []optimus.Row{{"name": "Ada", "grade": 9}, {"name": "Grace"}}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+
+	tests.Stop(t, NewStructs([]student{{Name: "Ada"}, {Name: "Grace"}}))
+
+	table = NewStructs([]int{1, 2})
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "EncodeRow needs a struct, got int")
+}
+
+func TestNewSeq(t *testing.T) {
+	// An infinite iterator stops once the Table is stopped.
+	table := NewSeq(func(yield func(student) bool) {
+		for i := 0; yield(student{Name: "Ada", Grade: i}); i++ {
+		}
+	})
+	rows := []optimus.Row{}
+	for row := range table.Rows() {
+		if rows = append(rows, row); len(rows) == 3 {
+			table.Stop()
+			break
+		}
+	}
+	// At most one more Row was already being sent when the Table was stopped.
+	assert.True(t, len(tests.GetRows(table)) <= 1)
+	assert.Equal(t, []optimus.Row{{"name": "Ada"}, {"name": "Ada", "grade": 1}, {"name": "Ada", "grade": 2}}, rows)
+}
+
+func TestConformance(t *testing.T) {
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return New([]optimus.Row{{"i": 1}, {"i": 2}, {"i": 3}})
+	})
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return NewStructs([]student{{Name: "Ada"}, {Name: "Grace"}})
+	})
+	tests.Conformance(t, func(optimus.Table) optimus.Table {
+		return NewSeq(func(yield func(student) bool) {
+			for i := 0; yield(student{Name: "Ada", Grade: i}); i++ {
+			}
+		})
+	})
+}
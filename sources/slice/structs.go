@@ -0,0 +1,44 @@
+package slice
+
+import (
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/internal/source"
+)
+
+type seqTable[T any] struct {
+	*source.Table
+}
+
+func (s *seqTable[T]) start(seq func(yield func(T) bool)) {
+	defer s.Close()
+	defer s.Recover("sources/slice")
+	seq(func(v T) bool {
+		row, err := optimus.EncodeRow(v)
+		if err != nil {
+			s.SetErr(err)
+			return false
+		}
+		return s.Send(row)
+	})
+}
+
+// NewStructs creates a new Table that sends each struct in an input slice as a Row, encoded with
+// optimus.EncodeRow.
+func NewStructs[T any](structs []T) optimus.Table {
+	return NewSeq(func(yield func(T) bool) {
+		for _, v := range structs {
+			if !yield(v) {
+				return
+			}
+		}
+	})
+}
+
+// NewSeq creates a new Table that sends each struct from an iterator as a Row, encoded with
+// optimus.EncodeRow. The iterator calls yield with each struct until it returns false, like an
+// iter.Seq, and yield returns false once the Table is stopped.
+func NewSeq[T any](seq func(yield func(T) bool)) optimus.Table {
+	table := &seqTable[T]{source.New()}
+	go table.start(seq)
+	return table
+}
@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// CompareMode is how CompareRows matches actual Rows to expected Rows.
+type CompareMode int
+
+const (
+	// Subset matches Rows by their index, and only compares the fields of the expected Rows, so
+	// the actual Rows can have extra fields. It's the default.
+	Subset CompareMode = iota
+	// Exact matches Rows by their index, and compares all their fields.
+	Exact
+	// Unordered matches Rows in any order, and compares all their fields. Each expected Row must
+	// match a different actual Row, so duplicate Rows have to appear the same number of times.
+	Unordered
+	// Keyed matches Rows by the value of Comparison.Key, in any order, and compares all their
+	// fields. Keys must be unique.
+	Keyed
+)
+
+// Comparison configures how CompareRows compares Rows.
+type Comparison struct {
+	Mode CompareMode
+	// Key identifies the Rows in Keyed mode. It has the same signature as transforms.RowIdentifier.
+	Key func(optimus.Row) (interface{}, error)
+	// FloatTolerance is how far apart numbers can be and still be equal. Numbers of different
+	// types, e.g. an int and a float64, are equal if they have close enough values. If it's 0,
+	// values must be exactly equal, like reflect.DeepEqual.
+	FloatTolerance float64
+}
+
+// CompareRows tests that actual matches expected, and reports every difference as a single error.
+// It returns whether they matched.
+func CompareRows(t assert.TestingT, expected, actual []optimus.Row, comparison Comparison) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	var diffs []string
+	switch comparison.Mode {
+	case Subset, Exact:
+		diffs = compareOrdered(expected, actual, comparison)
+	case Unordered:
+		diffs = compareUnordered(expected, actual, comparison)
+	case Keyed:
+		diffs = compareKeyed(expected, actual, comparison)
+	default:
+		diffs = []string{fmt.Sprintf("unknown CompareMode %d", comparison.Mode)}
+	}
+	if len(diffs) == 0 {
+		return true
+	}
+	t.Errorf("Rows differ:\n\t%s", strings.Join(diffs, "\n\t"))
+	return false
+}
+
+func compareOrdered(expected, actual []optimus.Row, comparison Comparison) []string {
+	var diffs []string
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		switch {
+		case i >= len(actual):
+			diffs = append(diffs, fmt.Sprintf("row %d: missing, expected %s", i, formatRow(expected[i])))
+		case i >= len(expected):
+			diffs = append(diffs, fmt.Sprintf("row %d: unexpected %s", i, formatRow(actual[i])))
+		default:
+			for _, diff := range diffRow(expected[i], actual[i], comparison) {
+				diffs = append(diffs, fmt.Sprintf("row %d: %s", i, diff))
+			}
+		}
+	}
+	return diffs
+}
+
+func compareUnordered(expected, actual []optimus.Row, comparison Comparison) []string {
+	matched := make([]bool, len(actual))
+	var diffs []string
+	for _, expectedRow := range expected {
+		found := false
+		for i, actualRow := range actual {
+			if !matched[i] && len(diffRow(expectedRow, actualRow, comparison)) == 0 {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			diffs = append(diffs, fmt.Sprintf("missing %s", formatRow(expectedRow)))
+		}
+	}
+	for i, actualRow := range actual {
+		if !matched[i] {
+			diffs = append(diffs, fmt.Sprintf("unexpected %s", formatRow(actualRow)))
+		}
+	}
+	return diffs
+}
+
+func compareKeyed(expected, actual []optimus.Row, comparison Comparison) []string {
+	if comparison.Key == nil {
+		return []string{"Keyed comparisons need a Key"}
+	}
+	var diffs []string
+	index := func(name string, rows []optimus.Row) (map[interface{}]optimus.Row, []interface{}) {
+		byKey := map[interface{}]optimus.Row{}
+		keys := []interface{}{}
+		for _, row := range rows {
+			key, err := comparison.Key(row)
+			if err != nil {
+				diffs = append(diffs, fmt.Sprintf("%s %s: %s", name, formatRow(row), err))
+				continue
+			}
+			if key != nil && !reflect.TypeOf(key).Comparable() {
+				diffs = append(diffs, fmt.Sprintf("%s %s: key %#v isn't comparable", name, formatRow(row), key))
+				continue
+			}
+			if _, ok := byKey[key]; ok {
+				diffs = append(diffs, fmt.Sprintf("%s key %#v: duplicate %s", name, key, formatRow(row)))
+				continue
+			}
+			byKey[key] = row
+			keys = append(keys, key)
+		}
+		return byKey, keys
+	}
+	expectedByKey, expectedKeys := index("expected", expected)
+	actualByKey, actualKeys := index("actual", actual)
+	for _, key := range expectedKeys {
+		actualRow, ok := actualByKey[key]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("key %#v: missing, expected %s", key, formatRow(expectedByKey[key])))
+			continue
+		}
+		for _, diff := range diffRow(expectedByKey[key], actualRow, comparison) {
+			diffs = append(diffs, fmt.Sprintf("key %#v: %s", key, diff))
+		}
+	}
+	for _, key := range actualKeys {
+		if _, ok := expectedByKey[key]; !ok {
+			diffs = append(diffs, fmt.Sprintf("key %#v: unexpected %s", key, formatRow(actualByKey[key])))
+		}
+	}
+	return diffs
+}
+
+// diffRow returns the differences between the fields of two Rows, sorted by field.
+func diffRow(expected, actual optimus.Row, comparison Comparison) []string {
+	var diffs []string
+	for _, field := range sortedFields(expected) {
+		actualValue, ok := actual[field]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("field %q: missing, expected %#v", field, expected[field]))
+		} else if !valuesEqual(expected[field], actualValue, comparison.FloatTolerance) {
+			diffs = append(diffs, fmt.Sprintf("field %q: expected %s, got %s", field,
+				formatValue(expected[field], actualValue), formatValue(actualValue, expected[field])))
+		}
+	}
+	if comparison.Mode == Subset {
+		return diffs
+	}
+	for _, field := range sortedFields(actual) {
+		if _, ok := expected[field]; !ok {
+			diffs = append(diffs, fmt.Sprintf("field %q: unexpected %#v", field, actual[field]))
+		}
+	}
+	return diffs
+}
+
+// valuesEqual is like reflect.DeepEqual, except that numbers within tolerance of each other are
+// equal if tolerance isn't 0.
+func valuesEqual(expected, actual interface{}, tolerance float64) bool {
+	if tolerance == 0 {
+		return reflect.DeepEqual(expected, actual)
+	}
+	if e, ok := toFloat(expected); ok {
+		a, ok := toFloat(actual)
+		return ok && (e == a || math.Abs(e-a) <= tolerance)
+	}
+	ev, av := reflect.ValueOf(expected), reflect.ValueOf(actual)
+	if !ev.IsValid() || !av.IsValid() || ev.Type() != av.Type() {
+		return reflect.DeepEqual(expected, actual)
+	}
+	switch ev.Kind() {
+	case reflect.Slice, reflect.Array:
+		if ev.Len() != av.Len() {
+			return false
+		}
+		for i := 0; i < ev.Len(); i++ {
+			if !valuesEqual(ev.Index(i).Interface(), av.Index(i).Interface(), tolerance) {
+				return false
+			}
+		}
+		return true
+	case reflect.Map:
+		if ev.Len() != av.Len() {
+			return false
+		}
+		for _, key := range ev.MapKeys() {
+			value := av.MapIndex(key)
+			if !value.IsValid() || !valuesEqual(ev.MapIndex(key).Interface(), value.Interface(), tolerance) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(expected, actual)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case !rv.IsValid():
+		return 0, false
+	case rv.CanInt():
+		return float64(rv.Int()), true
+	case rv.CanUint():
+		return float64(rv.Uint()), true
+	case rv.CanFloat():
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func sortedFields(row optimus.Row) []string {
+	fields := make([]string, 0, len(row))
+	for field := range row {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// formatValue formats a value with Go syntax, and adds its type if it would otherwise look the
+// same as other, e.g. for an int and a float64.
+func formatValue(value, other interface{}) string {
+	formatted := fmt.Sprintf("%#v", value)
+	if formatted == fmt.Sprintf("%#v", other) {
+		return fmt.Sprintf("%s (%T)", formatted, value)
+	}
+	return formatted
+}
+
+// formatRow formats a Row with its fields in order and the Go syntax for their values.
+func formatRow(row optimus.Row) string {
+	fields := make([]string, 0, len(row))
+	for _, field := range sortedFields(row) {
+		fields = append(fields, fmt.Sprintf("%q: %#v", field, row[field]))
+	}
+	return "{" + strings.Join(fields, ", ") + "}"
+}
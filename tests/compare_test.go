@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder records the errors reported to it, so that failing comparisons can be tested.
+type recorder struct {
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func byID(row optimus.Row) (interface{}, error) {
+	return row["id"], nil
+}
+
+var compareRowsTests = []struct {
+	desc       string
+	expected   []optimus.Row
+	actual     []optimus.Row
+	comparison Comparison
+	err        string
+}{
+	{
+		desc:     "subset ignores extra fields",
+		expected: []optimus.Row{{"id": 1}},
+		actual:   []optimus.Row{{"id": 1, "name": "Ada"}},
+	},
+	{
+		desc:     "subset reports missing and extra rows",
+		expected: []optimus.Row{{"id": 1}, {"id": 2}},
+		actual:   []optimus.Row{{"id": 1}, {"id": 3}, {"id": 4}},
+		err: "Rows differ:\n" +
+			"\trow 1: field \"id\": expected 2, got 3\n" +
+			"\trow 2: unexpected {\"id\": 4}",
+	},
+	{
+		desc:       "exact reports extra fields",
+		expected:   []optimus.Row{{"id": 1}},
+		actual:     []optimus.Row{{"id": 1, "name": "Ada"}},
+		comparison: Comparison{Mode: Exact},
+		err:        "Rows differ:\n\trow 0: field \"name\": unexpected \"Ada\"",
+	},
+	{
+		desc:       "exact reports missing fields",
+		expected:   []optimus.Row{{"id": 1, "name": "Ada"}, {"id": 2}},
+		actual:     []optimus.Row{{"id": 1}},
+		comparison: Comparison{Mode: Exact},
+		err: "Rows differ:\n" +
+			"\trow 0: field \"name\": missing, expected \"Ada\"\n" +
+			"\trow 1: missing, expected {\"id\": 2}",
+	},
+	{
+		desc:       "unordered matches rows in any order",
+		expected:   []optimus.Row{{"id": 1}, {"id": 2}, {"id": 2}},
+		actual:     []optimus.Row{{"id": 2}, {"id": 1}, {"id": 2}},
+		comparison: Comparison{Mode: Unordered},
+	},
+	{
+		desc:       "unordered counts duplicates",
+		expected:   []optimus.Row{{"id": 1}, {"id": 1}},
+		actual:     []optimus.Row{{"id": 1}, {"id": 2}},
+		comparison: Comparison{Mode: Unordered},
+		err:        "Rows differ:\n\tmissing {\"id\": 1}\n\tunexpected {\"id\": 2}",
+	},
+	{
+		desc:       "keyed matches rows by key",
+		expected:   []optimus.Row{{"id": 1, "name": "Ada"}, {"id": 2, "name": "Grace"}},
+		actual:     []optimus.Row{{"id": 2, "name": "Grace"}, {"id": 1, "name": "Ada"}},
+		comparison: Comparison{Mode: Keyed, Key: byID},
+	},
+	{
+		desc:       "keyed reports differences by key",
+		expected:   []optimus.Row{{"id": 1, "name": "Ada"}, {"id": 2, "name": "Grace"}},
+		actual:     []optimus.Row{{"id": 3}, {"id": 1, "name": "Alan"}, {"id": 3}},
+		comparison: Comparison{Mode: Keyed, Key: byID},
+		err: "Rows differ:\n" +
+			"\tactual key 3: duplicate {\"id\": 3}\n" +
+			"\tkey 1: field \"name\": expected \"Ada\", got \"Alan\"\n" +
+			"\tkey 2: missing, expected {\"id\": 2, \"name\": \"Grace\"}\n" +
+			"\tkey 3: unexpected {\"id\": 3}",
+	},
+	{
+		desc:       "keyed needs a key",
+		comparison: Comparison{Mode: Keyed},
+		err:        "Rows differ:\n\tKeyed comparisons need a Key",
+	},
+	{
+		desc:       "float tolerance",
+		expected:   []optimus.Row{{"a": 0.3, "b": 1, "c": []interface{}{1.0}, "d": optimus.Row{"e": 2.0}}},
+		actual:     []optimus.Row{{"a": 0.1 + 0.2, "b": 1.0000001, "c": []interface{}{1}, "d": optimus.Row{"e": 2.0000001}}},
+		comparison: Comparison{Mode: Exact, FloatTolerance: 1e-6},
+	},
+	{
+		desc:       "float tolerance is exceeded",
+		expected:   []optimus.Row{{"a": 1, "b": "1"}},
+		actual:     []optimus.Row{{"a": 1.1, "b": 1}},
+		comparison: Comparison{FloatTolerance: 1e-6},
+		err: "Rows differ:\n" +
+			"\trow 0: field \"a\": expected 1, got 1.1\n" +
+			"\trow 0: field \"b\": expected \"1\", got 1",
+	},
+	{
+		desc:     "no tolerance needs equal types",
+		expected: []optimus.Row{{"a": 1}},
+		actual:   []optimus.Row{{"a": 1.0}},
+		err:      "Rows differ:\n\trow 0: field \"a\": expected 1 (int), got 1 (float64)",
+	},
+}
+
+func TestCompareRows(t *testing.T) {
+	for _, compareRowsTest := range compareRowsTests {
+		r := &recorder{}
+		matched := CompareRows(r, compareRowsTest.expected, compareRowsTest.actual, compareRowsTest.comparison)
+		if compareRowsTest.err == "" {
+			assert.True(t, matched, compareRowsTest.desc)
+			assert.Empty(t, r.errors, compareRowsTest.desc)
+		} else {
+			assert.False(t, matched, compareRowsTest.desc)
+			assert.Equal(t, []string{compareRowsTest.err}, r.errors, compareRowsTest.desc)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package tests
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// conformanceTimeout is how long Conformance waits for a Table to close its Rows or for its
+// goroutines to exit.
+const conformanceTimeout = 5 * time.Second
+
+// conformanceMaxRows is how many Rows Conformance reads from a Table before it assumes that the
+// Table is infinite.
+const conformanceMaxRows = 10000
+
+// upstreamRows is how many Rows the upstream Table that Conformance passes to factory sends.
+const upstreamRows = 100
+
+// Conformance tests that the Tables created by factory behave the way every Table should:
+//
+//   - Stop can be called any number of times, from any goroutine.
+//   - Stop is passed on to the upstream Table, if the Table reads from one.
+//   - Rows is closed once the Table has sent all its Rows, and soon after it's stopped.
+//   - Err can be called from any goroutine while the Table is running. Run the tests with -race to
+//     check this.
+//   - No goroutines are left running once the Table is done, whether it was read to the end or
+//     stopped without being read at all.
+//
+// factory is called for each check with a new upstream Table, which sends 100 Rows with a
+// different "i" field each. Sources can ignore it. Conformance counts the running goroutines, so
+// it shouldn't run in parallel with other tests.
+func Conformance(t *testing.T, factory func(upstream optimus.Table) optimus.Table) {
+	t.Run("Stop is idempotent", func(t *testing.T) {
+		table := factory(newSpyTable())
+		var wg sync.WaitGroup
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				table.Stop()
+			}()
+		}
+		wg.Wait()
+		table.Stop()
+		if !closesAfterStop(table) {
+			t.Errorf("Rows wasn't closed within %s of Stop", conformanceTimeout)
+		}
+	})
+
+	t.Run("Stop is passed upstream", func(t *testing.T) {
+		upstream := newSpyTable()
+		table := factory(upstream)
+		readOne(table)
+		table.Stop()
+		if !closesAfterStop(table) {
+			t.Fatalf("Rows wasn't closed within %s of Stop", conformanceTimeout)
+		}
+		if upstream.used() && !eventually(upstream.wasStopped) {
+			t.Errorf("the upstream Table was read, but wasn't stopped")
+		}
+	})
+
+	t.Run("Rows is closed", func(t *testing.T) {
+		table := factory(newSpyTable())
+		finished, sending := readAll(table)
+		switch {
+		case finished:
+			if _, ok := <-table.Rows(); ok {
+				t.Errorf("Rows sent another Row after it was closed")
+			}
+		case sending:
+			// The Table seems to be infinite, so it can only be closed by stopping it.
+			table.Stop()
+			if !closesAfterStop(table) {
+				t.Errorf("Rows wasn't closed within %s of Stop", conformanceTimeout)
+			}
+		default:
+			t.Errorf("Rows wasn't closed within %s of its last Row", time.Second)
+			table.Stop()
+		}
+	})
+
+	t.Run("Err is race-free", func(t *testing.T) {
+		table := factory(newSpyTable())
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					table.Err()
+					runtime.Gosched()
+				}
+			}
+		}()
+		for i := 0; i < 10; i++ {
+			readOne(table)
+		}
+		table.Stop()
+		closesAfterStop(table)
+		table.Err()
+		close(done)
+		wg.Wait()
+	})
+
+	t.Run("no goroutines are leaked after Stop", func(t *testing.T) {
+		var table optimus.Table
+		NoLeakedGoroutines(t, func() {
+			table = factory(newSpyTable())
+			readOne(table)
+			// Nothing reads from the Table after it's stopped, so its goroutines mustn't block
+			// sending.
+			table.Stop()
+		})
+		closesAfterStop(table)
+	})
+
+	t.Run("no goroutines are leaked after reading every Row", func(t *testing.T) {
+		finished := true
+		NoLeakedGoroutines(t, func() {
+			table := factory(newSpyTable())
+			if finished, _ = readAll(table); !finished {
+				table.Stop()
+				closesAfterStop(table)
+			}
+		})
+		if !finished {
+			t.Skip("the Table didn't finish, so it can't be read to the end")
+		}
+	})
+}
+
+// NoLeakedGoroutines tests that every goroutine that fn starts has exited within a few seconds of
+// fn returning. It counts the running goroutines, so it shouldn't run in parallel with other
+// tests.
+func NoLeakedGoroutines(t assert.TestingT, fn func()) bool {
+	baseline := runtime.NumGoroutine()
+	fn()
+	if !eventually(func() bool { return runtime.NumGoroutine() <= baseline }) {
+		t.Errorf("%d goroutines were still running %s later:\n%s",
+			runtime.NumGoroutine()-baseline, conformanceTimeout, stacks())
+		return false
+	}
+	return true
+}
+
+// spyTable is a Table that sends upstreamRows Rows, and records whether it was read and stopped.
+// It doesn't start sending until Rows is called, so that it doesn't leak a goroutine when it's
+// ignored.
+type spyTable struct {
+	rows    chan optimus.Row
+	m       sync.Mutex
+	read    bool
+	stopped bool
+	stopCh  chan struct{}
+}
+
+func newSpyTable() *spyTable {
+	return &spyTable{rows: make(chan optimus.Row), stopCh: make(chan struct{})}
+}
+
+func (s *spyTable) start() {
+	defer close(s.rows)
+	for i := 0; i < upstreamRows; i++ {
+		select {
+		case s.rows <- optimus.Row{"i": i}:
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *spyTable) Rows() <-chan optimus.Row {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if !s.read {
+		s.read = true
+		go s.start()
+	}
+	return s.rows
+}
+
+func (s *spyTable) Err() error {
+	return nil
+}
+
+func (s *spyTable) Stop() {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if !s.stopped {
+		s.stopped = true
+		close(s.stopCh)
+	}
+}
+
+func (s *spyTable) used() bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.read
+}
+
+func (s *spyTable) wasStopped() bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.stopped
+}
+
+// readOne reads a Row from a Table, unless it's closed or doesn't send one in time.
+func readOne(table optimus.Table) {
+	select {
+	case <-table.Rows():
+	case <-time.After(conformanceTimeout):
+	}
+}
+
+// readAll reads up to conformanceMaxRows Rows from a Table for up to conformanceTimeout, and
+// returns whether the Table was closed, and if it wasn't, whether it was still sending Rows.
+func readAll(table optimus.Table) (finished bool, sending bool) {
+	timeout := time.After(conformanceTimeout)
+	last := time.Now()
+	for n := 0; n < conformanceMaxRows; n++ {
+		select {
+		case _, ok := <-table.Rows():
+			if !ok {
+				return true, false
+			}
+			last = time.Now()
+		case <-timeout:
+			return false, time.Since(last) < time.Second
+		}
+	}
+	return false, true
+}
+
+// closesAfterStop drains a stopped Table, and returns whether it was closed in time.
+func closesAfterStop(table optimus.Table) bool {
+	timeout := time.After(conformanceTimeout)
+	for {
+		select {
+		case _, ok := <-table.Rows():
+			if !ok {
+				return true
+			}
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+// eventually returns whether cond becomes true within conformanceTimeout.
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(conformanceTimeout)
+	for wait := time.Millisecond; !cond(); wait = min(2*wait, 100*time.Millisecond) {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(wait)
+	}
+	return true
+}
+
+// stacks returns the stacks of all running goroutines.
+func stacks() string {
+	buf := make([]byte, 1<<20)
+	return string(buf[:runtime.Stack(buf, true)])
+}
@@ -0,0 +1,227 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/stretchr/testify/assert"
+)
+
+// updateFlag is the name of the flag that makes the golden helpers write their golden files instead
+// of comparing with them, e.g. go test ./transforms -update.
+const updateFlag = "update"
+
+func init() {
+	// Only test binaries get the flag, and one that's already defined, e.g. by a package's own
+	// tests, is used as it is.
+	if testing.Testing() && flag.Lookup(updateFlag) == nil {
+		flag.Bool(updateFlag, false, "update the golden files in testdata")
+	}
+}
+
+// floatDigits is how many significant digits floats are rounded to in golden files, so that
+// rounding errors like 0.1 + 0.2 don't change them.
+const floatDigits = 10
+
+// GoldenNDJSON tests that the Rows of a Table, serialized as newline-delimited JSON, match the
+// golden file testdata/name. Each Row is one line of JSON with its keys sorted, and floats rounded
+// to 10 significant digits. When the tests are run with -update, the golden file is written
+// instead.
+func GoldenNDJSON(t assert.TestingT, table optimus.Table, name string) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	var buf bytes.Buffer
+	for row := range table.Rows() {
+		line, err := json.Marshal(canonicalValue(row))
+		if err != nil {
+			t.Errorf("couldn't serialize %s: %s", formatRow(row), err)
+			return false
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := table.Err(); err != nil {
+		t.Errorf("table failed: %s", err)
+		return false
+	}
+	return golden(t, buf.Bytes(), name)
+}
+
+// GoldenCSV tests that the Rows of a Table, serialized as CSV, match the golden file
+// testdata/name. The header is every field of every Row, sorted, and missing and nil fields are
+// empty. Floats are rounded to 10 significant digits like GoldenNDJSON, and arrays and objects are
+// serialized as JSON. When the tests are run with -update, the golden file is written instead.
+func GoldenCSV(t assert.TestingT, table optimus.Table, name string) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	rows := GetRows(table)
+	if err := table.Err(); err != nil {
+		t.Errorf("table failed: %s", err)
+		return false
+	}
+	fieldSet := map[string]bool{}
+	for _, row := range rows {
+		for field := range row {
+			fieldSet[field] = true
+		}
+	}
+	fields := make([]string, 0, len(fieldSet))
+	for field := range fieldSet {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(fields)
+	for _, row := range rows {
+		record := make([]string, len(fields))
+		for i, field := range fields {
+			value, err := csvValue(row[field])
+			if err != nil {
+				t.Errorf("couldn't serialize %s: %s", formatRow(row), err)
+				return false
+			}
+			record[i] = value
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		t.Errorf("couldn't write CSV: %s", err)
+		return false
+	}
+	return golden(t, buf.Bytes(), name)
+}
+
+// golden compares actual with the golden file testdata/name, or writes it with -update.
+func golden(t assert.TestingT, actual []byte, name string) bool {
+	path := filepath.Join("testdata", name)
+	if updateGolden() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Errorf("couldn't update golden file: %s", err)
+			return false
+		}
+		if err := os.WriteFile(path, actual, 0644); err != nil {
+			t.Errorf("couldn't update golden file: %s", err)
+			return false
+		}
+		return true
+	}
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("couldn't read golden file, run the tests with -update to create it: %s", err)
+		return false
+	}
+	if bytes.Equal(expected, actual) {
+		return true
+	}
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(expected)),
+		B:        difflib.SplitLines(string(actual)),
+		FromFile: path,
+		ToFile:   "actual",
+		Context:  3,
+	})
+	t.Errorf("output doesn't match %s, run the tests with -update to update it:\n%s", path, diff)
+	return false
+}
+
+// updateGolden returns whether the golden files should be written, because the tests are run with
+// -update.
+func updateGolden() bool {
+	f := flag.Lookup(updateFlag)
+	if f == nil {
+		return false
+	}
+	update, ok := f.Value.(flag.Getter).Get().(bool)
+	return ok && update
+}
+
+// canonicalValue converts a value into one that serializes the same way as JSON whenever the value
+// is the same: floats are rounded, and times are in UTC.
+func canonicalValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case float32:
+		return canonicalFloat(float64(v), 32)
+	case float64:
+		return canonicalFloat(v, 64)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case json.Marshaler:
+		return v
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return value
+		}
+		out := make(map[string]interface{}, rv.Len())
+		for _, key := range rv.MapKeys() {
+			out[key.String()] = canonicalValue(rv.MapIndex(key).Interface())
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// Byte slices are serialized as base64 strings.
+			return value
+		}
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = canonicalValue(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+		return canonicalValue(rv.Elem().Interface())
+	}
+	return value
+}
+
+// canonicalFloat rounds a float to floatDigits significant digits. JSON can't represent NaN and
+// infinities, so they're strings.
+func canonicalFloat(f float64, bitSize int) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	// Format the shortest representation for the float's size first, so that float32s aren't
+	// padded with the digits of their float64 conversion.
+	shortest, _ := strconv.ParseFloat(strconv.FormatFloat(f, 'g', -1, bitSize), 64)
+	return json.Number(strconv.FormatFloat(shortest, 'g', floatDigits, 64))
+}
+
+func csvValue(value interface{}) (string, error) {
+	switch v := canonicalValue(value).(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v), nil
+	default:
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	}
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// rowTable is a Table of Rows, since the slice source can't be imported here.
+type rowTable struct {
+	rows chan optimus.Row
+}
+
+func newRowTable(rows ...optimus.Row) *rowTable {
+	t := &rowTable{rows: make(chan optimus.Row, len(rows))}
+	for _, row := range rows {
+		t.rows <- row
+	}
+	close(t.rows)
+	return t
+}
+
+func (t *rowTable) Rows() <-chan optimus.Row { return t.rows }
+func (t *rowTable) Err() error               { return nil }
+func (t *rowTable) Stop()                    {}
+
+func goldenRows() []optimus.Row {
+	return []optimus.Row{
+		{"name": "Ada", "grade": 9, "gpa": 0.1 + 0.2, "tags": []string{"b", "a"}},
+		{"name": "Grace", "gpa": float32(3.7), "address": optimus.Row{"zip": "94110", "city": "SF"}},
+		{"name": "Alan", "enrolled": time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("PST", -8*3600)),
+			"score": math.Inf(1), "note": "says \"hi\", twice\n", "nothing": nil},
+	}
+}
+
+func TestGoldenNDJSON(t *testing.T) {
+	GoldenNDJSON(t, newRowTable(goldenRows()...), "golden.ndjson")
+}
+
+func TestGoldenCSV(t *testing.T) {
+	GoldenCSV(t, newRowTable(goldenRows()...), "golden.csv")
+}
+
+func TestGoldenMismatch(t *testing.T) {
+	if updateGolden() {
+		t.Skip("golden files are being updated")
+	}
+	rows := goldenRows()
+	rows[1]["name"] = "Hopper"
+	r := &recorder{}
+	assert.False(t, GoldenNDJSON(r, newRowTable(rows...), "golden.ndjson"))
+	if assert.Len(t, r.errors, 1) {
+		assert.Contains(t, r.errors[0], "output doesn't match testdata/golden.ndjson")
+		assert.Contains(t, r.errors[0], "--- testdata/golden.ndjson\n+++ actual\n")
+		assert.Contains(t, r.errors[0], `-{"address":{"city":"SF","zip":"94110"},"gpa":3.7,"name":"Grace"}`)
+		assert.Contains(t, r.errors[0], `+{"address":{"city":"SF","zip":"94110"},"gpa":3.7,"name":"Hopper"}`)
+	}
+
+	r = &recorder{}
+	assert.False(t, GoldenCSV(r, newRowTable(goldenRows()...), "missing.csv"))
+	if assert.Len(t, r.errors, 1) {
+		assert.True(t, strings.HasPrefix(r.errors[0], "couldn't read golden file, run the tests with -update to create it"))
+	}
+}
@@ -45,29 +45,33 @@ type TableCompareConfig struct {
 	Expected func(optimus.Table, interface{}) optimus.Table
 	Arg      interface{}
 	Error    error
+	// Comparison configures how the Rows of the actual Table are compared to the expected ones.
+	// By default, they're compared in order, and only the fields of the expected Rows are checked.
+	Comparison Comparison
 }
 
 // CompareTables takes in a config of comparisons and runs them
 func CompareTables(t *testing.T, configs []TableCompareConfig) {
+	t.Helper()
 	for _, config := range configs {
 		if config.Source == nil {
 			config.Source = func() optimus.Table {
 				return nil
 			}
 		}
+		if config.Expected == nil && config.Error == nil {
+			t.Errorf("%s: config has neither Expected nor Error", config.Name)
+			continue
+		}
 		actualTable := config.Actual(config.Source(), config.Arg)
 		actual := GetRows(actualTable)
 		if config.Expected != nil {
 			expected := GetRows(config.Expected(config.Source(), config.Arg))
-			for idx, expectedRow := range expected {
-				for fieldName := range expectedRow {
-					assert.Equal(t, expected[idx][fieldName], actual[idx][fieldName], "%s failed", config.Name)
-				}
+			if !CompareRows(t, expected, actual, config.Comparison) {
+				t.Errorf("%s failed", config.Name)
 			}
-		} else if config.Error != nil {
-			assert.Equal(t, config.Error, actualTable.Err())
 		} else {
-			t.Fatalf("what are you doing?? config has neither expected nor error: %#v", config)
+			assert.Equal(t, config.Error, actualTable.Err(), "%s failed", config.Name)
 		}
 	}
 }
@@ -0,0 +1,158 @@
+package transformer
+
+import (
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/transforms"
+)
+
+// A Pipeline is a sequence of stages that can be defined once and applied to any number of
+// Tables. Unlike a Transformer, a Pipeline is never modified: each of its methods returns a new
+// Pipeline, so the same Pipeline can be extended in different ways, e.g.
+//
+//	normalize := transformer.Pipeline{}.Fieldmap(mappings).Valuemap(values)
+//	students := normalize.Select(isStudent).Named("students")
+//	teachers := normalize.Select(isTeacher).Named("teachers")
+//
+// The zero Pipeline has no stages, and is ready to use. Each stage is named after the method that
+// added it, unless it's renamed with Named.
+//
+// Transforms that read from another Table, like Join and Concat, aren't Pipeline methods, since a
+// Table can only be read once. Apply them to a Transformer instead.
+type Pipeline struct {
+	stages []Stage
+}
+
+// Stages returns the stages of the Pipeline, in order.
+func (p Pipeline) Stages() []Stage {
+	return append([]Stage{}, p.stages...)
+}
+
+// Run returns a Table that provides the Rows of table transformed by every stage of the Pipeline.
+func (p Pipeline) Run(table optimus.Table) optimus.Table {
+	return New(table).Pipeline(p).Table()
+}
+
+// Then returns a Pipeline with the stages of next after the stages of p.
+func (p Pipeline) Then(next Pipeline) Pipeline {
+	// Copy the stages, so that they're never shared with another Pipeline that could append to
+	// them.
+	stages := make([]Stage, 0, len(p.stages)+len(next.stages))
+	return Pipeline{append(append(stages, p.stages...), next.stages...)}
+}
+
+// Named returns a Pipeline with its last stage renamed to name. It returns the same Pipeline if
+// there are no stages.
+func (p Pipeline) Named(name string) Pipeline {
+	if len(p.stages) == 0 {
+		return p
+	}
+	stages := p.Stages()
+	stages[len(stages)-1].Name = name
+	return Pipeline{stages}
+}
+
+// Plan returns a description of the Pipeline's stages.
+func (p Pipeline) Plan() Plan {
+	return Plan{Stages: p.Stages()}
+}
+
+func (p Pipeline) with(stage Stage) Pipeline {
+	return p.Then(Pipeline{[]Stage{stage}})
+}
+
+// Apply returns a Pipeline with a stage that applies the TransformFunc.
+func (p Pipeline) Apply(transform optimus.TransformFunc) Pipeline {
+	return p.with(newStage("Apply", transform))
+}
+
+// Fieldmap returns a Pipeline with a Fieldmap stage.
+func (p Pipeline) Fieldmap(mappings map[string][]string) Pipeline {
+	return p.with(newStage("Fieldmap", transforms.Fieldmap(mappings), param("mappings", mappings)))
+}
+
+// SafeFieldmap returns a Pipeline with a SafeFieldmap stage.
+func (p Pipeline) SafeFieldmap(mappings map[string][]string) Pipeline {
+	return p.with(newStage("SafeFieldmap", transforms.SafeFieldmap(mappings),
+		param("mappings", mappings)))
+}
+
+// Map returns a Pipeline with a Map stage.
+func (p Pipeline) Map(transform func(optimus.Row) (optimus.Row, error)) Pipeline {
+	return p.with(newStage("Map", transforms.Map(transform), param("transform", transform)))
+}
+
+// Each returns a Pipeline with an Each stage.
+func (p Pipeline) Each(transform func(optimus.Row) error) Pipeline {
+	return p.with(newStage("Each", transforms.Each(transform), param("transform", transform)))
+}
+
+// Fuse returns a Pipeline with a Fuse stage.
+func (p Pipeline) Fuse(steps ...transforms.RowStep) Pipeline {
+	return p.with(newStage("Fuse", transforms.Fuse(steps...), param("steps", steps)))
+}
+
+// TableTransform returns a Pipeline with a TableTransform stage.
+func (p Pipeline) TableTransform(transform func(optimus.Row, chan<- optimus.Row) error) Pipeline {
+	return p.with(newStage("TableTransform", transforms.TableTransform(transform),
+		param("transform", transform)))
+}
+
+// Select returns a Pipeline with a Select stage.
+func (p Pipeline) Select(filter func(optimus.Row) (bool, error)) Pipeline {
+	return p.with(newStage("Select", transforms.Select(filter), param("filter", filter)))
+}
+
+// Validate returns a Pipeline with a Validate stage.
+func (p Pipeline) Validate(check func(optimus.Row) error, limit int) Pipeline {
+	return p.with(newStage("Validate", transforms.Validate(check, limit), param("check", check),
+		param("limit", limit)))
+}
+
+// Valuemap returns a Pipeline with a Valuemap stage.
+func (p Pipeline) Valuemap(mappings map[string]map[interface{}]interface{}) Pipeline {
+	return p.with(newStage("Valuemap", transforms.Valuemap(mappings), param("mappings", mappings)))
+}
+
+// Reduce returns a Pipeline with a Reduce stage.
+func (p Pipeline) Reduce(fn func(optimus.Row, optimus.Row) error) Pipeline {
+	return p.with(newStage("Reduce", transforms.Reduce(fn), param("fn", fn)))
+}
+
+// Concurrently returns a Pipeline with a Concurrently stage.
+func (p Pipeline) Concurrently(fn optimus.TransformFunc, concurrency int) Pipeline {
+	return p.with(newStage("Concurrently", transforms.Concurrently(fn, concurrency),
+		param("fn", fn), param("concurrency", concurrency)))
+}
+
+// Sort returns a Pipeline with a Sort stage.
+func (p Pipeline) Sort(less func(i, j optimus.Row) (bool, error)) Pipeline {
+	return p.with(newStage("Sort", transforms.Sort(less), param("less", less)))
+}
+
+// StableSort returns a Pipeline with a StableSort stage.
+func (p Pipeline) StableSort(less func(i, j optimus.Row) (bool, error)) Pipeline {
+	return p.with(newStage("StableSort", transforms.StableSort(less), param("less", less)))
+}
+
+// StableCompressedSort returns a Pipeline with a StableCompressedSort stage.
+func (p Pipeline) StableCompressedSort(getKey transforms.RowIdentifier) Pipeline {
+	return p.with(newStage("StableCompressedSort", transforms.StableCompressedSort(getKey),
+		param("getKey", getKey)))
+}
+
+// GroupBy returns a Pipeline with a GroupBy stage.
+func (p Pipeline) GroupBy(identifier transforms.RowIdentifier) Pipeline {
+	return p.with(newStage("GroupBy", transforms.GroupBy(identifier),
+		param("identifier", identifier)))
+}
+
+// Unique returns a Pipeline with a Unique stage.
+func (p Pipeline) Unique(hash transforms.RowIdentifier) Pipeline {
+	return p.with(newStage("Unique", transforms.Unique(hash), param("hash", hash)))
+}
+
+// BypassTransforms returns a Pipeline with a BypassTransforms stage.
+func (p Pipeline) BypassTransforms(doBypass transforms.RowFilter, optionalTransforms []optimus.TransformFunc) Pipeline {
+	return p.with(newStage("BypassTransforms", transforms.BypassTransforms(doBypass, optionalTransforms),
+		param("doBypass", doBypass), param("optionalTransforms", optionalTransforms)))
+}
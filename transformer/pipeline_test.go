@@ -0,0 +1,121 @@
+package transformer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/Clever/optimus/v4/transforms"
+	"github.com/stretchr/testify/assert"
+)
+
+func stageNames(p Pipeline) []string {
+	names := []string{}
+	for _, stage := range p.Stages() {
+		names = append(names, stage.Name)
+	}
+	return names
+}
+
+var notValue3 = func(row optimus.Row) (bool, error) {
+	return row["header1"] != "value3", nil
+}
+
+func TestPipelineRun(t *testing.T) {
+	pipeline := Pipeline{}.Fieldmap(map[string][]string{"header1": {"header3"}}).Select(
+		func(row optimus.Row) (bool, error) { return row["header3"] != "value3", nil })
+	expected := []optimus.Row{{"header3": "value1"}, {"header3": "value5"}}
+	// A Pipeline can be run on any number of Tables.
+	for i := 0; i < 2; i++ {
+		table := pipeline.Run(defaultSource())
+		assert.Equal(t, expected, tests.GetRows(table))
+		assert.Nil(t, table.Err())
+	}
+	table := New(defaultSource()).Pipeline(pipeline).Map(func(row optimus.Row) (optimus.Row, error) {
+		return optimus.Row{"header4": row["header3"]}, nil
+	}).Table()
+	assert.Equal(t, []optimus.Row{{"header4": "value1"}, {"header4": "value5"}}, tests.GetRows(table))
+}
+
+func TestPipelineIsImmutable(t *testing.T) {
+	base := Pipeline{}.Select(notValue3)
+	mapped := base.Map(func(row optimus.Row) (optimus.Row, error) {
+		return optimus.Row{"mapped": row["header1"]}, nil
+	}).Named("rename")
+	each := base.Each(func(optimus.Row) error { return nil })
+	assert.Equal(t, []string{"Select"}, stageNames(base))
+	assert.Equal(t, []string{"Select", "rename"}, stageNames(mapped))
+	assert.Equal(t, []string{"Select", "Each"}, stageNames(each))
+
+	assert.Equal(t, []optimus.Row{{"header1": "value1", "header2": "value2"}, {"header1": "value5", "header2": "value6"}},
+		tests.GetRows(base.Run(defaultSource())))
+	assert.Equal(t, []optimus.Row{{"mapped": "value1"}, {"mapped": "value5"}},
+		tests.GetRows(mapped.Run(defaultSource())))
+
+	// Changing the stages that are returned doesn't change the Pipeline.
+	base.Stages()[0].Name = "changed"
+	assert.Equal(t, []string{"Select"}, stageNames(base))
+}
+
+func TestPipelineThen(t *testing.T) {
+	first := Pipeline{}.Fieldmap(map[string][]string{"header1": {"a"}}).Named("first")
+	second := Pipeline{}.Valuemap(map[string]map[interface{}]interface{}{"a": {"value1": "one"}})
+	both := first.Then(second).Then(Pipeline{})
+	assert.Equal(t, []string{"first", "Valuemap"}, stageNames(both))
+	assert.Equal(t, []optimus.Row{{"a": "one"}, {"a": "value3"}, {"a": "value5"}},
+		tests.GetRows(both.Run(defaultSource())))
+	assert.Equal(t, []string{}, stageNames(Pipeline{}.Named("nothing")))
+}
+
+func TestPipelineError(t *testing.T) {
+	table := Pipeline{}.Map(errorTransform("failed")).Sort(func(i, j optimus.Row) (bool, error) {
+		return false, nil
+	}).Run(defaultSource())
+	tests.HasRows(t, table, 0)
+	assert.Equal(t, errors.New("failed"), table.Err())
+}
+
+func TestPipelineConformance(t *testing.T) {
+	pipeline := Pipeline{}.Fieldmap(map[string][]string{"i": {"i"}}).Unique(transforms.KeyIdentifier("i"))
+	tests.Conformance(t, pipeline.Run)
+}
+
+func TestPipelineStages(t *testing.T) {
+	byHeader := transforms.KeyIdentifier("header1")
+	pipeline := Pipeline{}.
+		Apply(transforms.Limit(10)).
+		SafeFieldmap(map[string][]string{"header1": {"header1"}, "header2": {"header2"}}).
+		Fuse(transforms.SelectStep(notValue3)).
+		TableTransform(func(row optimus.Row, out chan<- optimus.Row) error {
+			out <- row
+			out <- row
+			return nil
+		}).
+		Unique(byHeader).
+		Concurrently(transforms.Each(func(optimus.Row) error { return nil }), 2).
+		StableCompressedSort(byHeader).
+		StableSort(func(i, j optimus.Row) (bool, error) { return false, nil }).
+		BypassTransforms(func(optimus.Row) bool { return true }, nil).
+		GroupBy(byHeader).
+		Reduce(func(accum, item optimus.Row) error {
+			count, _ := accum["groups"].(int)
+			accum["groups"] = count + 1
+			return nil
+		})
+	assert.Equal(t, []string{"Apply", "SafeFieldmap", "Fuse", "TableTransform", "Unique", "Concurrently",
+		"StableCompressedSort", "StableSort", "BypassTransforms", "GroupBy", "Reduce"}, stageNames(pipeline))
+	table := pipeline.Run(defaultSource())
+	assert.Equal(t, []optimus.Row{{"groups": 2}}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+}
+
+func TestPipelinePanic(t *testing.T) {
+	table := Pipeline{}.Fieldmap(map[string][]string{"header1": {"a"}}).Map(
+		func(optimus.Row) (optimus.Row, error) { panic("bad row") }).Named("explode").
+		Run(defaultSource())
+	tests.HasRows(t, table, 0)
+	if assert.IsType(t, &optimus.PanicError{}, table.Err()) {
+		assert.Equal(t, "explode", table.Err().(*optimus.PanicError).Stage)
+	}
+}
@@ -0,0 +1,249 @@
+package transformer
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// Param is a parameter of a Stage, described as a string. Functions are described by the name of
+// the function that declares them, e.g. "transforms.KeyIdentifier" or "main.main".
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Stage is a named TransformFunc in a Pipeline or Transformer.
+type Stage struct {
+	// Name is the Stage's name, which is its Type unless it's been renamed with Named.
+	Name string
+	// Type is the kind of transform, which is the name of the method that added it, e.g. "Map".
+	Type string
+	// Params describes the arguments of the transform.
+	Params []Param
+	// Inputs are the Plans of the other Tables that the transform reads from, e.g. the right
+	// Table of a Join.
+	Inputs    []Plan
+	Transform optimus.TransformFunc
+}
+
+func newStage(typ string, transform optimus.TransformFunc, params ...Param) Stage {
+	return Stage{Name: typ, Type: typ, Params: params, Transform: transform}
+}
+
+func param(name string, value interface{}) Param {
+	return Param{Name: name, Value: describe(value)}
+}
+
+func (s Stage) withInputs(tables ...optimus.Table) Stage {
+	for _, table := range tables {
+		if planned, ok := table.(*plannedTable); ok {
+			s.Inputs = append(s.Inputs, planned.plan.clone())
+		} else {
+			s.Inputs = append(s.Inputs, Plan{Source: describeTable(table)})
+		}
+	}
+	return s
+}
+
+// A Plan describes a pipeline: the Table it reads from, its stages in order, and the sink that
+// consumes it.
+type Plan struct {
+	// Source describes the Table that the pipeline reads from. By default, it's the package of a
+	// Table from this module, e.g. "sources/csv", or the type of any other Table.
+	Source string
+	Stages []Stage
+	// Sink is the name of the function that declares the sink, e.g. "sinks/json.New", once the
+	// pipeline has been sunk.
+	Sink string
+}
+
+func (p Plan) clone() Plan {
+	p.Stages = append([]Stage(nil), p.Stages...)
+	for i := range p.Stages {
+		p.Stages[i].Params = append([]Param(nil), p.Stages[i].Params...)
+		inputs := p.Stages[i].Inputs
+		p.Stages[i].Inputs = nil
+		for _, input := range inputs {
+			p.Stages[i].Inputs = append(p.Stages[i].Inputs, input.clone())
+		}
+	}
+	return p
+}
+
+// Explain returns a description of the Plan as text, with one line for the source, each stage and
+// the sink. The Plans of a stage's inputs are indented under it, e.g.
+//
+//	source: sources/csv
+//	1. Fieldmap mappings=map[id:[id] name:[name]]
+//	2. schools: Join leftHeader="school_id" rightHeader="id" join=Left
+//	   input 1:
+//	     source: sources/json
+//	     1. Select filter=main.isOpen
+//	sink: sinks/json.New
+func (p Plan) Explain() string {
+	var b strings.Builder
+	p.explain(&b, "")
+	return b.String()
+}
+
+func (p Plan) explain(b *strings.Builder, indent string) {
+	if p.Source != "" {
+		fmt.Fprintf(b, "%ssource: %s\n", indent, p.Source)
+	}
+	for i, stage := range p.Stages {
+		fmt.Fprintf(b, "%s%d. %s\n", indent, i+1, stage.label(" "))
+		for j, input := range stage.Inputs {
+			fmt.Fprintf(b, "%s   input %d:\n", indent, j+1)
+			input.explain(b, indent+"     ")
+		}
+	}
+	if p.Sink != "" {
+		fmt.Fprintf(b, "%ssink: %s\n", indent, p.Sink)
+	}
+}
+
+// label describes the Stage, with sep between its name and each of its Params.
+func (s Stage) label(sep string) string {
+	label := s.Type
+	if s.Name != s.Type {
+		label = s.Name + ": " + s.Type
+	}
+	for _, param := range s.Params {
+		label += sep + param.Name + "=" + param.Value
+	}
+	return label
+}
+
+// DOT returns the Plan as a Graphviz DOT graph, e.g. for rendering with dot -Tsvg. The source and
+// sink are ellipses, and each stage is a box with its Params. The inputs of a stage are drawn as
+// branches that lead into it.
+func (p Plan) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph pipeline {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	nodes := 0
+	p.dot(&b, &nodes)
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dot writes the nodes and edges of the Plan, and returns the ID of its last node, or "" if it
+// doesn't have any.
+func (p Plan) dot(b *strings.Builder, nodes *int) string {
+	node := func(label, shape string) string {
+		*nodes++
+		id := fmt.Sprintf("n%d", *nodes)
+		fmt.Fprintf(b, "\t%s [label=%s", id, dotQuote(label))
+		if shape != "" {
+			fmt.Fprintf(b, ", shape=%s", shape)
+		}
+		b.WriteString("];\n")
+		return id
+	}
+	edge := func(from, to, label string) {
+		if from == "" {
+			return
+		}
+		if label == "" {
+			fmt.Fprintf(b, "\t%s -> %s;\n", from, to)
+		} else {
+			fmt.Fprintf(b, "\t%s -> %s [label=%s];\n", from, to, dotQuote(label))
+		}
+	}
+
+	last := ""
+	if p.Source != "" {
+		last = node(p.Source, "ellipse")
+	}
+	for _, stage := range p.Stages {
+		id := node(stage.label("\n"), "")
+		edge(last, id, "")
+		for i, input := range stage.Inputs {
+			edge(input.dot(b, nodes), id, fmt.Sprintf("input %d", i+1))
+		}
+		last = id
+	}
+	if p.Sink != "" {
+		id := node(p.Sink, "ellipse")
+		edge(last, id, "")
+		last = id
+	}
+	return last
+}
+
+// dotQuote quotes a string as a DOT ID.
+func dotQuote(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+// plannedTable is a Table that was built by a Transformer, and knows its Plan.
+type plannedTable struct {
+	optimus.Table
+	plan Plan
+}
+
+// modulePrefix is trimmed from the names of the packages in this module.
+const modulePrefix = "github.com/Clever/optimus/v4/"
+
+// describeTable describes a Table that wasn't built by a Transformer.
+func describeTable(table optimus.Table) string {
+	t := reflect.TypeOf(table)
+	if t == nil {
+		return "nil"
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if strings.HasPrefix(t.PkgPath(), modulePrefix) {
+		return strings.TrimPrefix(t.PkgPath(), modulePrefix)
+	}
+	return reflect.TypeOf(table).String()
+}
+
+// closureSuffix matches the suffixes of the names of closures and method values, e.g. ".func1"
+// and "-fm".
+var closureSuffix = regexp.MustCompile(`(\.func\d+|\.gowrap\d+|-fm)+$`)
+
+// funcName returns the name of the function that declares fn.
+func funcName(fn interface{}) string {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return "nil"
+	}
+	f := runtime.FuncForPC(v.Pointer())
+	if f == nil {
+		return "unknown"
+	}
+	return closureSuffix.ReplaceAllString(strings.TrimPrefix(f.Name(), modulePrefix), "")
+}
+
+// describe describes a parameter of a Stage.
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return fmt.Sprintf("%q", v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Func:
+		return funcName(value)
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Func {
+			names := make([]string, rv.Len())
+			for i := range names {
+				names[i] = funcName(rv.Index(i).Interface())
+			}
+			return "[" + strings.Join(names, " ") + "]"
+		}
+	}
+	return fmt.Sprintf("%v", value)
+}
@@ -0,0 +1,80 @@
+package transformer
+
+import (
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	sliceSink "github.com/Clever/optimus/v4/sinks/slice"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/transforms"
+	"github.com/stretchr/testify/assert"
+)
+
+func isOpen(row optimus.Row) (bool, error) {
+	return row["open"] == true, nil
+}
+
+// explainedTransformer returns a Transformer with a branch, named stages, and a Pipeline.
+func explainedTransformer() *Transformer {
+	schools := New(slice.New([]optimus.Row{{"id": "1", "open": true}})).Select(isOpen).Named("open schools")
+	normalize := Pipeline{}.Valuemap(map[string]map[interface{}]interface{}{"grade": {"K": 0}})
+	return New(slice.New([]optimus.Row{{"school_id": "1", "name": "Ada", "grade": "K"}})).Named("students").
+		Fieldmap(map[string][]string{"school_id": {"school_id"}, "grade": {"grade"}}).
+		Pipeline(normalize).
+		Join(schools.Table(), "school_id", "id", transforms.JoinType.Left).Named("schools").
+		Concurrently(transforms.Each(func(optimus.Row) error { return nil }), 4)
+}
+
+func TestExplain(t *testing.T) {
+	transformer := explainedTransformer()
+	var rows []optimus.Row
+	assert.Nil(t, transformer.Sink(sliceSink.New(&rows)))
+	assert.Equal(t, []optimus.Row{{"school_id": "1", "grade": 0, "id": "1", "open": true}}, rows)
+	assert.Equal(t, `source: students
+1. Fieldmap mappings=map[grade:[grade] school_id:[school_id]]
+2. Valuemap mappings=map[grade:map[K:0]]
+3. schools: Join leftHeader="school_id" rightHeader="id" join=Left
+   input 1:
+     source: sources/slice
+     1. open schools: Select filter=transformer.isOpen
+4. Concurrently fn=transforms.TableTransform concurrency=4
+sink: sinks/slice.New
+`, transformer.Plan().Explain())
+}
+
+func TestDOT(t *testing.T) {
+	assert.Equal(t, `digraph pipeline {
+	rankdir=LR;
+	node [shape=box];
+	n1 [label="students", shape=ellipse];
+	n2 [label="Fieldmap\nmappings=map[grade:[grade] school_id:[school_id]]"];
+	n1 -> n2;
+	n3 [label="Valuemap\nmappings=map[grade:map[K:0]]"];
+	n2 -> n3;
+	n4 [label="schools: Join\nleftHeader=\"school_id\"\nrightHeader=\"id\"\njoin=Left"];
+	n3 -> n4;
+	n5 [label="sources/slice", shape=ellipse];
+	n6 [label="open schools: Select\nfilter=transformer.isOpen"];
+	n5 -> n6;
+	n6 -> n4 [label="input 1"];
+	n7 [label="Concurrently\nfn=transforms.TableTransform\nconcurrency=4"];
+	n4 -> n7;
+}
+`, explainedTransformer().Plan().DOT())
+}
+
+func TestPlanContinuesAcrossTransformers(t *testing.T) {
+	first := New(slice.New(nil)).Apply(transforms.Limit(1))
+	second := New(first.Table()).Unique(transforms.KeyIdentifier("id"))
+	assert.Equal(t, "source: sources/slice\n1. Apply\n2. Unique hash=transforms.KeyIdentifier\n",
+		second.Plan().Explain())
+	// Adding stages to the second Transformer doesn't change the first one's Plan.
+	assert.Equal(t, "source: sources/slice\n1. Apply\n", first.Plan().Explain())
+}
+
+func TestPipelinePlan(t *testing.T) {
+	pipeline := Pipeline{}.Sort(func(i, j optimus.Row) (bool, error) { return false, nil }).Named("by nothing")
+	assert.Equal(t, "1. by nothing: Sort less=transformer.TestPipelinePlan\n", pipeline.Plan().Explain())
+	assert.Equal(t, "digraph pipeline {\n\trankdir=LR;\n\tnode [shape=box];\n"+
+		"\tn1 [label=\"by nothing: Sort\\nless=transformer.TestPipelinePlan\"];\n}\n", pipeline.Plan().DOT())
+}
@@ -5,95 +5,213 @@ import (
 	"github.com/Clever/optimus/v4/transforms"
 )
 
-// A Transformer allows you to easily chain multiple transforms on a table.
+// A Transformer allows you to easily chain multiple transforms on a table. It records what each
+// transform is as its Plan, so that the pipeline can be explained.
 type Transformer struct {
-	table optimus.Table
+	table   optimus.Table
+	options []optimus.TransformOption
+	plan    Plan
 }
 
-// Table returns the terminating Table in a Transformer chain.
+// Table returns the terminating Table in a Transformer chain. If it's the source of another
+// Transformer, or an input of a transform like Join, the other Transformer's Plan includes this
+// one's.
 func (t Transformer) Table() optimus.Table {
-	return t.table
+	return &plannedTable{Table: t.table, plan: t.Plan()}
 }
 
-// Apply applies a given TransformFunc to the Transformer.
+// Plan returns a description of the pipeline that the Transformer has built so far.
+func (t Transformer) Plan() Plan {
+	return t.plan.clone()
+}
+
+// Named names the last stage that was applied, or the source if none have been, in the
+// Transformer's Plan. The stage has already started, so its panics are reported with its Type. Use
+// Pipeline.Named for names in errors.
+func (t *Transformer) Named(name string) *Transformer {
+	if len(t.plan.Stages) == 0 {
+		t.plan.Source = name
+	} else {
+		t.plan.Stages[len(t.plan.Stages)-1].Name = name
+	}
+	return t
+}
+
+// Apply applies a given TransformFunc to the Transformer. It modifies the Transformer, so see
+// Pipeline for chains of transforms that can be reused.
 func (t *Transformer) Apply(transform optimus.TransformFunc) *Transformer {
-	// TODO: Should this return a new transformer instead of modifying the existing one?
-	t.table = optimus.Transform(t.table, transform)
+	return t.apply(newStage("Apply", transform))
+}
+
+// apply applies a Stage. A panic in the Stage fails the Table with an *optimus.PanicError that
+// has the Stage's name, so it has to be named before it's applied, e.g. by a Pipeline.
+func (t *Transformer) apply(stage Stage) *Transformer {
+	options := append(t.options[:len(t.options):len(t.options)], optimus.StageName(stage.Name))
+	t.table = optimus.Transform(t.table, stage.Transform, options...)
+	t.plan.Stages = append(t.plan.Stages, stage)
+	return t
+}
+
+// BufferSize buffers the channels of the transforms applied after it, with room for size Rows
+// each. See optimus.BufferSize.
+func (t *Transformer) BufferSize(size int) *Transformer {
+	t.options = append(t.options, optimus.BufferSize(size))
+	return t
+}
+
+// CollectErrors collects the errors of the transforms applied after it, and of the Table they
+// read from, instead of stopping at the first one. The Table's Err is an *optimus.MultiError with
+// up to limit errors. See optimus.CollectErrors.
+func (t *Transformer) CollectErrors(limit int) *Transformer {
+	t.options = append(t.options, optimus.CollectErrors(limit))
 	return t
 }
 
+// Fuse Applies a Fuse transform, which runs consecutive row-wise transforms in a single stage. E.g.
+// t.Fuse(transforms.MapStep(fn), transforms.SelectStep(filter)) is equivalent to
+// t.Map(fn).Select(filter), with one less stage.
+func (t *Transformer) Fuse(steps ...transforms.RowStep) *Transformer {
+	return t.apply(newStage("Fuse", transforms.Fuse(steps...), param("steps", steps)))
+}
+
 // Fieldmap Applies a Fieldmap transform.
 func (t *Transformer) Fieldmap(mappings map[string][]string) *Transformer {
-	return t.Apply(transforms.Fieldmap(mappings))
+	return t.apply(newStage("Fieldmap", transforms.Fieldmap(mappings), param("mappings", mappings)))
 }
 
 // Map Applies a Map transform.
 func (t *Transformer) Map(transform func(optimus.Row) (optimus.Row, error)) *Transformer {
-	return t.Apply(transforms.Map(transform))
+	return t.apply(newStage("Map", transforms.Map(transform), param("transform", transform)))
 }
 
 // Each Applies an Each transform.
 func (t *Transformer) Each(transform func(optimus.Row) error) *Transformer {
-	return t.Apply(transforms.Each(transform))
+	return t.apply(newStage("Each", transforms.Each(transform), param("transform", transform)))
 }
 
 // TableTransform Applies a TableTransform transform.
 func (t *Transformer) TableTransform(transform func(optimus.Row, chan<- optimus.Row) error) *Transformer {
-	return t.Apply(transforms.TableTransform(transform))
+	return t.apply(newStage("TableTransform", transforms.TableTransform(transform),
+		param("transform", transform)))
 }
 
 // Select Applies a Select transform.
 func (t *Transformer) Select(filter func(optimus.Row) (bool, error)) *Transformer {
-	return t.Apply(transforms.Select(filter))
+	return t.apply(newStage("Select", transforms.Select(filter), param("filter", filter)))
+}
+
+// Validate Applies a Validate transform.
+func (t *Transformer) Validate(check func(optimus.Row) error, limit int) *Transformer {
+	return t.apply(newStage("Validate", transforms.Validate(check, limit), param("check", check),
+		param("limit", limit)))
 }
 
 // Valuemap Applies a Valuemap transform.
 func (t *Transformer) Valuemap(mappings map[string]map[interface{}]interface{}) *Transformer {
-	return t.Apply(transforms.Valuemap(mappings))
+	return t.apply(newStage("Valuemap", transforms.Valuemap(mappings), param("mappings", mappings)))
 }
 
 // Reduce Applies a Reduce transform.
 func (t *Transformer) Reduce(fn func(optimus.Row, optimus.Row) error) *Transformer {
-	return t.Apply(transforms.Reduce(fn))
+	return t.apply(newStage("Reduce", transforms.Reduce(fn), param("fn", fn)))
 }
 
 // Concurrently Applies a Concurrent transform.
 func (t *Transformer) Concurrently(fn optimus.TransformFunc, concurrency int) *Transformer {
-	return t.Apply(transforms.Concurrently(fn, concurrency))
+	return t.apply(newStage("Concurrently", transforms.Concurrently(fn, concurrency),
+		param("fn", fn), param("concurrency", concurrency)))
 }
 
 // Concat Applies a Concat transform.
 func (t *Transformer) Concat(tables ...optimus.Table) *Transformer {
-	return t.Apply(transforms.Concat(tables...))
+	return t.apply(newStage("Concat", transforms.Concat(tables...)).withInputs(tables...))
 }
 
 // Pair Applies a Pair transform.
 func (t *Transformer) Pair(rightTable optimus.Table, leftID, rightID transforms.RowIdentifier,
 	filterFn func(optimus.Row) (bool, error)) *Transformer {
-	return t.Apply(transforms.Pair(rightTable, leftID, rightID, filterFn))
+	return t.apply(newStage("Pair", transforms.Pair(rightTable, leftID, rightID, filterFn),
+		param("leftID", leftID), param("rightID", rightID),
+		param("filterFn", filterFn)).withInputs(rightTable))
 }
 
 // Sort Applies a Sort transform.
 func (t *Transformer) Sort(less func(i, j optimus.Row) (bool, error)) *Transformer {
-	return t.Apply(transforms.Sort(less))
+	return t.apply(newStage("Sort", transforms.Sort(less), param("less", less)))
 }
 
 // StableSort Applies a StableSort transform.
 func (t *Transformer) StableSort(less func(i, j optimus.Row) (bool, error)) *Transformer {
-	return t.Apply(transforms.StableSort(less))
+	return t.apply(newStage("StableSort", transforms.StableSort(less), param("less", less)))
 }
 
 // GroupBy Applies a GroupBy transform.
 func (t *Transformer) GroupBy(identifier transforms.RowIdentifier) *Transformer {
-	return t.Apply(transforms.GroupBy(identifier))
+	return t.apply(newStage("GroupBy", transforms.GroupBy(identifier),
+		param("identifier", identifier)))
+}
+
+// SafeFieldmap Applies a SafeFieldmap transform.
+func (t *Transformer) SafeFieldmap(mappings map[string][]string) *Transformer {
+	return t.apply(newStage("SafeFieldmap", transforms.SafeFieldmap(mappings),
+		param("mappings", mappings)))
+}
+
+// Join Applies a Join transform.
+func (t *Transformer) Join(rightTable optimus.Table, leftHeader, rightHeader string,
+	join transforms.JoinKind) *Transformer {
+	return t.apply(newStage("Join", transforms.Join(rightTable, leftHeader, rightHeader, join),
+		param("leftHeader", leftHeader), param("rightHeader", rightHeader),
+		param("join", join)).withInputs(rightTable))
+}
+
+// JoinBy Applies a JoinBy transform.
+func (t *Transformer) JoinBy(rightTable optimus.Table, leftID, rightID transforms.RowIdentifier,
+	join transforms.JoinKind, merge transforms.MergeFunc) *Transformer {
+	return t.apply(newStage("JoinBy", transforms.JoinBy(rightTable, leftID, rightID, join, merge),
+		param("leftID", leftID), param("rightID", rightID), param("join", join),
+		param("merge", merge)).withInputs(rightTable))
+}
+
+// Unique Applies a Unique transform.
+func (t *Transformer) Unique(hash transforms.RowIdentifier) *Transformer {
+	return t.apply(newStage("Unique", transforms.Unique(hash), param("hash", hash)))
+}
+
+// StableCompressedSort Applies a StableCompressedSort transform.
+func (t *Transformer) StableCompressedSort(getKey transforms.RowIdentifier) *Transformer {
+	return t.apply(newStage("StableCompressedSort", transforms.StableCompressedSort(getKey),
+		param("getKey", getKey)))
+}
+
+// BypassTransforms Applies a BypassTransforms transform.
+func (t *Transformer) BypassTransforms(doBypass transforms.RowFilter,
+	optionalTransforms []optimus.TransformFunc) *Transformer {
+	return t.apply(newStage("BypassTransforms", transforms.BypassTransforms(doBypass, optionalTransforms),
+		param("doBypass", doBypass), param("optionalTransforms", optionalTransforms)))
+}
+
+// Pipeline applies every stage of a Pipeline.
+func (t *Transformer) Pipeline(pipeline Pipeline) *Transformer {
+	for _, stage := range pipeline.stages {
+		t.apply(stage)
+	}
+	return t
 }
 
 // Sink consumes all the Rows.
 func (t *Transformer) Sink(sink optimus.Sink) error {
+	t.plan.Sink = funcName(sink)
 	return sink(t.table)
 }
 
 // New returns a Transformer that allows you to chain transformations on a Table.
 func New(table optimus.Table) *Transformer {
-	return &Transformer{table}
+	if planned, ok := table.(*plannedTable); ok {
+		// Continue the other Transformer's Plan, instead of treating its Table as the source.
+		plan := planned.plan.clone()
+		plan.Sink = ""
+		return &Transformer{table: planned.Table, plan: plan}
+	}
+	return &Transformer{table: table, plan: Plan{Source: describeTable(table)}}
 }
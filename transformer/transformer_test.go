@@ -2,6 +2,7 @@ package transformer
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/Clever/optimus/v4"
@@ -112,6 +113,24 @@ var chainedEqualities = []tests.TableCompareConfig{
 			return row["header1"] == "value1", nil
 		},
 	},
+	{
+		Name:   "Validate",
+		Source: defaultSource,
+		Actual: func(source optimus.Table, arg interface{}) optimus.Table {
+			check := arg.(func(optimus.Row) error)
+			return New(source).Validate(check, 1).Table()
+		},
+		Expected: func(source optimus.Table, arg interface{}) optimus.Table {
+			check := arg.(func(optimus.Row) error)
+			return optimus.Transform(source, transforms.Validate(check, 1))
+		},
+		Arg: func(row optimus.Row) error {
+			if row["header1"] == "value1" {
+				return nil
+			}
+			return fmt.Errorf("invalid header1 %v", row["header1"])
+		},
+	},
 	{
 		Name:   "Valuemap",
 		Source: defaultSource,
@@ -127,6 +146,122 @@ var chainedEqualities = []tests.TableCompareConfig{
 			"header1": {"value1": "value10", "value3": "value30"},
 		},
 	},
+	{
+		Name:   "Fuse",
+		Source: defaultSource,
+		Actual: func(source optimus.Table, arg interface{}) optimus.Table {
+			mappings := arg.(map[string][]string)
+			return New(source).Fuse(transforms.FieldmapStep(mappings), transforms.SelectStep(
+				func(row optimus.Row) (bool, error) { return row["header4"] != "value3", nil })).Table()
+		},
+		Expected: func(source optimus.Table, arg interface{}) optimus.Table {
+			mappings := arg.(map[string][]string)
+			return New(source).Fieldmap(mappings).Select(
+				func(row optimus.Row) (bool, error) { return row["header4"] != "value3", nil }).Table()
+		},
+		Arg: map[string][]string{"header1": {"header4"}},
+	},
+	{
+		Name:   "BufferSize",
+		Source: defaultSource,
+		Actual: func(source optimus.Table, arg interface{}) optimus.Table {
+			mappings := arg.(map[string][]string)
+			return New(source).BufferSize(2).Fieldmap(mappings).Fieldmap(mappings).Table()
+		},
+		Expected: func(source optimus.Table, arg interface{}) optimus.Table {
+			mappings := arg.(map[string][]string)
+			return New(source).Fieldmap(mappings).Fieldmap(mappings).Table()
+		},
+		Arg: map[string][]string{"header1": {"header1"}},
+	},
+	{
+		Name:   "CollectErrors",
+		Source: defaultSource,
+		Actual: func(source optimus.Table, arg interface{}) optimus.Table {
+			mappings := arg.(map[string][]string)
+			return New(source).CollectErrors(5).Fieldmap(mappings).Table()
+		},
+		Expected: func(source optimus.Table, arg interface{}) optimus.Table {
+			mappings := arg.(map[string][]string)
+			return New(source).Fieldmap(mappings).Table()
+		},
+		Arg: map[string][]string{"header1": {"header4"}},
+	},
+	{
+		Name:   "SafeFieldmap",
+		Source: defaultSource,
+		Actual: func(source optimus.Table, arg interface{}) optimus.Table {
+			return New(source).SafeFieldmap(arg.(map[string][]string)).Table()
+		},
+		Expected: func(source optimus.Table, arg interface{}) optimus.Table {
+			return optimus.Transform(source, transforms.SafeFieldmap(arg.(map[string][]string)))
+		},
+		Arg: map[string][]string{"header1": {"header4"}},
+	},
+	{
+		Name:   "Join",
+		Source: defaultSource,
+		Actual: func(source optimus.Table, arg interface{}) optimus.Table {
+			return New(source).Join(slice.New(arg.([]optimus.Row)), "header1", "header1", transforms.JoinType.Inner).Table()
+		},
+		Expected: func(source optimus.Table, arg interface{}) optimus.Table {
+			return optimus.Transform(source, transforms.Join(slice.New(arg.([]optimus.Row)), "header1", "header1",
+				transforms.JoinType.Inner))
+		},
+		Arg: []optimus.Row{{"header1": "value3", "header3": "joined"}},
+	},
+	{
+		Name:   "JoinBy",
+		Source: defaultSource,
+		Actual: func(source optimus.Table, arg interface{}) optimus.Table {
+			byHeader := transforms.KeyIdentifier("header1")
+			return New(source).JoinBy(slice.New(arg.([]optimus.Row)), byHeader, byHeader,
+				transforms.JoinType.Left, transforms.LeftWins).Table()
+		},
+		Expected: func(source optimus.Table, arg interface{}) optimus.Table {
+			byHeader := transforms.KeyIdentifier("header1")
+			return optimus.Transform(source, transforms.JoinBy(slice.New(arg.([]optimus.Row)), byHeader, byHeader,
+				transforms.JoinType.Left, transforms.LeftWins))
+		},
+		Arg: []optimus.Row{{"header1": "value3", "header2": "joined"}},
+	},
+	{
+		Name:   "Unique",
+		Source: defaultSource,
+		Actual: func(source optimus.Table, arg interface{}) optimus.Table {
+			return New(source).Unique(arg.(transforms.RowIdentifier)).Table()
+		},
+		Expected: func(source optimus.Table, arg interface{}) optimus.Table {
+			return optimus.Transform(source, transforms.Unique(arg.(transforms.RowIdentifier)))
+		},
+		Arg: transforms.RowIdentifier(func(optimus.Row) (interface{}, error) { return 1, nil }),
+	},
+	{
+		Name:   "StableCompressedSort",
+		Source: defaultSource,
+		Actual: func(source optimus.Table, arg interface{}) optimus.Table {
+			return New(source).StableCompressedSort(arg.(transforms.RowIdentifier)).Table()
+		},
+		Expected: func(source optimus.Table, arg interface{}) optimus.Table {
+			return optimus.Transform(source, transforms.StableCompressedSort(arg.(transforms.RowIdentifier)))
+		},
+		Arg: transforms.RowIdentifier(func(row optimus.Row) (interface{}, error) {
+			return "-" + row["header2"].(string), nil
+		}),
+	},
+	{
+		Name:   "BypassTransforms",
+		Source: defaultSource,
+		Actual: func(source optimus.Table, arg interface{}) optimus.Table {
+			return New(source).BypassTransforms(func(row optimus.Row) bool { return row["header1"] == "value1" },
+				arg.([]optimus.TransformFunc)).Table()
+		},
+		Expected: func(source optimus.Table, arg interface{}) optimus.Table {
+			return optimus.Transform(source, transforms.BypassTransforms(
+				func(row optimus.Row) bool { return row["header1"] == "value1" }, arg.([]optimus.TransformFunc)))
+		},
+		Arg: []optimus.TransformFunc{transforms.Fieldmap(map[string][]string{"header2": {"header2"}})},
+	},
 	{
 		Name: "TableTransformErrorPassesThrough",
 		Actual: func(optimus.Table, interface{}) optimus.Table {
@@ -0,0 +1,71 @@
+package transforms
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// Batch returns a TransformFunc that groups consecutive Rows into batches. A batch is sent once it
+// has size Rows, or once interval has passed since its first Row was received, whichever comes
+// first. An interval of zero means batches are only sent when they're full. The last batch is sent
+// when the input is done, even if it isn't full.
+// Each output Row has a single field, rows, which is the slice of Rows in the batch, e.g.
+// optimus.Row{"rows": []optimus.Row{{"id": 1}, {"id": 2}}}
+// The batches are still Rows of a Table, e.g. for a Sink that writes them. To send batches instead
+// of Rows, for fewer channel sends per Row, use an optimus.BatchTable.
+func Batch(size int, interval time.Duration) optimus.TransformFunc {
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		if size < 1 {
+			return fmt.Errorf("Batch size must be at least 1, got %d", size)
+		}
+		batch := []optimus.Row{}
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		flush := func() {
+			if timer != nil {
+				timer.Stop()
+				timer, timeout = nil, nil
+			}
+			if len(batch) > 0 {
+				out <- optimus.Row{"rows": batch}
+				batch = []optimus.Row{}
+			}
+		}
+		for {
+			select {
+			case row, ok := <-in:
+				if !ok {
+					flush()
+					return nil
+				}
+				batch = append(batch, row)
+				if len(batch) == 1 && interval > 0 {
+					timer = time.NewTimer(interval)
+					timeout = timer.C
+				}
+				if len(batch) >= size {
+					flush()
+				}
+			case <-timeout:
+				flush()
+			}
+		}
+	}
+}
+
+// Unbatch returns a TransformFunc that undoes Batch: it sends each of the Rows in the rows field
+// of every input Row. It also works on the output of GroupBy.
+func Unbatch() optimus.TransformFunc {
+	return TableTransform(func(row optimus.Row, out chan<- optimus.Row) error {
+		rows, ok := row["rows"].([]optimus.Row)
+		if !ok {
+			return fmt.Errorf("rows field must be a []optimus.Row, had value: %#v", row["rows"])
+		}
+		for _, r := range rows {
+			out <- r
+		}
+		return nil
+	})
+}
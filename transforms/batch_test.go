@@ -0,0 +1,64 @@
+package transforms
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatch(t *testing.T) {
+	input := numberedRows(5)
+	table := optimus.Transform(slice.New(input), Batch(2, 0))
+	assert.Equal(t, []optimus.Row{
+		{"rows": input[0:2]},
+		{"rows": input[2:4]},
+		{"rows": input[4:5]},
+	}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+
+	table = optimus.Transform(optimus.Transform(slice.New(input), Batch(2, 0)), Unbatch())
+	assert.Equal(t, input, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+
+	table = optimus.Transform(slice.New([]optimus.Row{}), Batch(2, 0))
+	tests.Consumed(t, table)
+	assert.Nil(t, table.Err())
+}
+
+// TestBatchInterval tests that a batch is sent once its interval has passed, even if the input
+// isn't done.
+func TestBatchInterval(t *testing.T) {
+	in := make(chan optimus.Row)
+	out := make(chan optimus.Row)
+	go func() {
+		defer close(out)
+		assert.Nil(t, Batch(100, 10*time.Millisecond)(in, out))
+	}()
+	in <- optimus.Row{"i": 0}
+	in <- optimus.Row{"i": 1}
+	select {
+	case batch := <-out:
+		assert.Equal(t, optimus.Row{"rows": numberedRows(2)}, batch)
+	case <-time.After(time.Second):
+		t.Fatal("expected a batch to be sent after the interval")
+	}
+	in <- optimus.Row{"i": 2}
+	close(in)
+	assert.Equal(t, optimus.Row{"rows": []optimus.Row{{"i": 2}}}, <-out)
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+func TestBatchErrors(t *testing.T) {
+	table := optimus.Transform(slice.New(numberedRows(5)), Batch(0, 0))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "Batch size must be at least 1, got 0")
+
+	table = optimus.Transform(slice.New(numberedRows(1)), Unbatch())
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "rows field must be a []optimus.Row, had value: <nil>")
+}
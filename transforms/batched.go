@@ -0,0 +1,52 @@
+package transforms
+
+import "github.com/Clever/optimus/v4"
+
+// FuseBatches returns a BatchTransformFunc that runs every Row of every batch through each of the
+// steps in turn, like Fuse. Each batch is sent with the Rows that all the steps keep, unless none
+// of them are kept. If a step fails, none of the Rows of its batch are sent.
+func FuseBatches(steps ...RowStep) optimus.BatchTransformFunc {
+	return func(in <-chan []optimus.Row, out chan<- []optimus.Row) error {
+		for batch := range in {
+			// The batch belongs to this transform once it's received, so it's reused for the Rows
+			// that are kept.
+			kept := batch[:0]
+			for _, row := range batch {
+				row, keep, err := runSteps(steps, row)
+				if err != nil {
+					return err
+				}
+				if keep {
+					kept = append(kept, row)
+				}
+			}
+			if len(kept) > 0 {
+				out <- kept
+			}
+		}
+		return nil
+	}
+}
+
+// BatchMap returns a BatchTransformFunc that transforms every Row with the given function, like Map.
+func BatchMap(transform func(optimus.Row) (optimus.Row, error)) optimus.BatchTransformFunc {
+	return FuseBatches(MapStep(transform))
+}
+
+// BatchSelect returns a BatchTransformFunc that removes any Rows that don't pass the filter, like
+// Select.
+func BatchSelect(filter func(optimus.Row) (bool, error)) optimus.BatchTransformFunc {
+	return FuseBatches(SelectStep(filter))
+}
+
+// BatchFieldmap returns a BatchTransformFunc that applies a field mapping to every Row, like
+// Fieldmap.
+func BatchFieldmap(mappings map[string][]string) optimus.BatchTransformFunc {
+	return FuseBatches(FieldmapStep(mappings))
+}
+
+// BatchValuemap returns a BatchTransformFunc that applies a value mapping to every Row, like
+// Valuemap.
+func BatchValuemap(mappings map[string]map[interface{}]interface{}) optimus.BatchTransformFunc {
+	return FuseBatches(ValuemapStep(mappings))
+}
@@ -0,0 +1,60 @@
+package transforms
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchTransforms(t *testing.T) {
+	even := func(row optimus.Row) (bool, error) { return row["i"].(int)%2 == 0, nil }
+	double := func(row optimus.Row) (optimus.Row, error) {
+		return optimus.Row{"i": row["i"].(int) * 2}, nil
+	}
+	fieldmap := map[string][]string{"i": {"j"}}
+	valuemap := map[string]map[interface{}]interface{}{"j": {0: "zero"}}
+	for _, size := range []int{1, 3, 100} {
+		expected := tests.GetRows(optimus.Transform(optimus.Transform(optimus.Transform(optimus.Transform(
+			slice.New(numberedRows(10)), Select(even)), Map(double)), Fieldmap(fieldmap)), Valuemap(valuemap)))
+
+		batches := optimus.Batched(slice.New(numberedRows(10)), size)
+		for _, transform := range []optimus.BatchTransformFunc{
+			BatchSelect(even), BatchMap(double), BatchFieldmap(fieldmap), BatchValuemap(valuemap),
+		} {
+			batches = optimus.TransformBatches(batches, transform)
+		}
+		actual := optimus.Unbatched(batches)
+		assert.Equal(t, expected, tests.GetRows(actual), "batches of %d", size)
+		assert.Nil(t, actual.Err())
+	}
+}
+
+func TestFuseBatchesDropsEmptyBatches(t *testing.T) {
+	batches := optimus.TransformBatches(optimus.Batched(slice.New(numberedRows(10)), 2),
+		BatchSelect(func(row optimus.Row) (bool, error) { return row["i"].(int) < 3, nil }))
+	var sizes []int
+	for batch := range batches.Rows() {
+		sizes = append(sizes, len(batch))
+	}
+	assert.Nil(t, batches.Err())
+	assert.Equal(t, []int{2, 1}, sizes)
+}
+
+func TestFuseBatchesError(t *testing.T) {
+	table := optimus.Unbatched(optimus.TransformBatches(optimus.Batched(slice.New(numberedRows(10)), 3),
+		BatchMap(func(row optimus.Row) (optimus.Row, error) {
+			if row["i"].(int) == 4 {
+				return nil, errors.New("failed")
+			}
+			return row, nil
+		})))
+	// The batch with the failing Row isn't sent. The Rows before it may be discarded with the error.
+	rows := tests.GetRows(table)
+	assert.Equal(t, numberedRows(len(rows)), rows)
+	assert.LessOrEqual(t, len(rows), 3)
+	assert.EqualError(t, table.Err(), "failed")
+}
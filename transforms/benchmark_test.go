@@ -0,0 +1,156 @@
+package transforms
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/slice"
+)
+
+const benchmarkRows = 1000
+
+var benchmarkDepths = []int{1, 5, 15}
+
+func benchmarkInput() []optimus.Row {
+	rows := make([]optimus.Row, benchmarkRows)
+	for i := range rows {
+		rows[i] = optimus.Row{"id": i, "name": fmt.Sprintf("row %d", i), "score": float64(i) / 3}
+	}
+	return rows
+}
+
+var byID = KeyIdentifier("id")
+
+// benchmarkStages returns a stage of a pipeline for each of the transforms that are benchmarked.
+// Each stage sends the same Rows it receives, so that they can be chained to any depth.
+func benchmarkStages(input []optimus.Row) map[string]func(optimus.Table, ...optimus.TransformOption) optimus.Table {
+	return map[string]func(optimus.Table, ...optimus.TransformOption) optimus.Table{
+		"Map": func(table optimus.Table, opts ...optimus.TransformOption) optimus.Table {
+			return optimus.Transform(table, Map(func(row optimus.Row) (optimus.Row, error) {
+				return row, nil
+			}), opts...)
+		},
+		"Select": func(table optimus.Table, opts ...optimus.TransformOption) optimus.Table {
+			return optimus.Transform(table, Select(func(row optimus.Row) (bool, error) {
+				return row["id"] != nil, nil
+			}), opts...)
+		},
+		"Sort": func(table optimus.Table, opts ...optimus.TransformOption) optimus.Table {
+			return optimus.Transform(table, Sort(func(i, j optimus.Row) (bool, error) {
+				return i["score"].(float64) < j["score"].(float64), nil
+			}), opts...)
+		},
+		// Pair's Rows are unwrapped to the left Row, so that the next Pair gets the same Rows.
+		"Pair": func(table optimus.Table, opts ...optimus.TransformOption) optimus.Table {
+			paired := optimus.Transform(table, Pair(slice.New(input), byID, byID, func(optimus.Row) (bool, error) {
+				return true, nil
+			}), opts...)
+			return optimus.Transform(paired, Map(func(row optimus.Row) (optimus.Row, error) {
+				return row["left"].(optimus.Row), nil
+			}), opts...)
+		},
+	}
+}
+
+// runBenchmark reads every Row of the pipeline that build returns, b.N times.
+func runBenchmark(b *testing.B, input []optimus.Row, build func(optimus.Table) optimus.Table) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		table := build(slice.New(input))
+		count := 0
+		for range table.Rows() {
+			count++
+		}
+		if err := table.Err(); err != nil {
+			b.Fatal(err)
+		}
+		if count != len(input) {
+			b.Fatalf("expected %d rows, got %d", len(input), count)
+		}
+	}
+	b.ReportMetric(float64(b.N*len(input))/b.Elapsed().Seconds(), "rows/s")
+}
+
+// BenchmarkChain benchmarks chains of each transform, with and without buffered channels.
+func BenchmarkChain(b *testing.B) {
+	input := benchmarkInput()
+	stages := benchmarkStages(input)
+	for _, name := range []string{"Map", "Select", "Sort", "Pair"} {
+		stage := stages[name]
+		for _, depth := range benchmarkDepths {
+			for _, bufferSize := range []int{0, 64} {
+				b.Run(fmt.Sprintf("%s/depth=%d/buffer=%d", name, depth, bufferSize), func(b *testing.B) {
+					runBenchmark(b, input, func(table optimus.Table) optimus.Table {
+						for i := 0; i < depth; i++ {
+							table = stage(table, optimus.BufferSize(bufferSize))
+						}
+						return table
+					})
+				})
+			}
+		}
+	}
+}
+
+// BenchmarkFuse benchmarks chains of alternating Maps and Selects, applied one after another and
+// fused into one stage.
+func BenchmarkFuse(b *testing.B) {
+	input := benchmarkInput()
+	identity := func(row optimus.Row) (optimus.Row, error) { return row, nil }
+	hasID := func(row optimus.Row) (bool, error) { return row["id"] != nil, nil }
+	for _, depth := range benchmarkDepths {
+		b.Run(fmt.Sprintf("chained/depth=%d", depth), func(b *testing.B) {
+			runBenchmark(b, input, func(table optimus.Table) optimus.Table {
+				for i := 0; i < depth; i++ {
+					if i%2 == 0 {
+						table = optimus.Transform(table, Map(identity))
+					} else {
+						table = optimus.Transform(table, Select(hasID))
+					}
+				}
+				return table
+			})
+		})
+		b.Run(fmt.Sprintf("fused/depth=%d", depth), func(b *testing.B) {
+			runBenchmark(b, input, func(table optimus.Table) optimus.Table {
+				steps := make([]RowStep, depth)
+				for i := range steps {
+					if i%2 == 0 {
+						steps[i] = MapStep(identity)
+					} else {
+						steps[i] = SelectStep(hasID)
+					}
+				}
+				return optimus.Transform(table, Fuse(steps...))
+			})
+		})
+	}
+}
+
+// BenchmarkBatches benchmarks chains of Maps, applied to Rows one at a time and to batches of them.
+func BenchmarkBatches(b *testing.B) {
+	input := benchmarkInput()
+	identity := func(row optimus.Row) (optimus.Row, error) { return row, nil }
+	for _, depth := range benchmarkDepths {
+		b.Run(fmt.Sprintf("rows/depth=%d", depth), func(b *testing.B) {
+			runBenchmark(b, input, func(table optimus.Table) optimus.Table {
+				for i := 0; i < depth; i++ {
+					table = optimus.Transform(table, Map(identity))
+				}
+				return table
+			})
+		})
+		for _, size := range []int{16, 256} {
+			b.Run(fmt.Sprintf("batches/size=%d/depth=%d", size, depth), func(b *testing.B) {
+				runBenchmark(b, input, func(table optimus.Table) optimus.Table {
+					batches := optimus.Batched(table, size)
+					for i := 0; i < depth; i++ {
+						batches = optimus.TransformBatches(batches, BatchMap(identity))
+					}
+					return optimus.Unbatched(batches)
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package transforms
+
+import "time"
+
+// Clock tells the time and waits. It allows transforms that depend on time, like rate limiting
+// and retries, to be tested without waiting.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After waits for the duration to elapse and then sends the current time on the returned
+	// channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// SystemClock is a Clock that uses the time package.
+var SystemClock Clock = systemClock{}
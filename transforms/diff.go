@@ -0,0 +1,229 @@
+package transforms
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// The kinds of change that Diff reports, in the change field of its output Rows.
+const (
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+// DiffOptions configures how Diff compares Rows.
+type DiffOptions struct {
+	// Ignore lists fields that aren't compared.
+	Ignore []string
+	// Equal reports whether a field's values are the same before and after. Nil means
+	// reflect.DeepEqual.
+	Equal func(field string, before, after interface{}) bool
+}
+
+type differ struct {
+	ignore map[string]bool
+	equal  func(field string, before, after interface{}) bool
+}
+
+func newDiffer(options DiffOptions) differ {
+	d := differ{ignore: map[string]bool{}, equal: options.Equal}
+	for _, field := range options.Ignore {
+		d.ignore[field] = true
+	}
+	if d.equal == nil {
+		d.equal = func(field string, before, after interface{}) bool {
+			return reflect.DeepEqual(before, after)
+		}
+	}
+	return d
+}
+
+// diff returns the Row describing the change from before to after, or nil if there wasn't one.
+// Either Row may be nil, meaning that it was added or removed.
+func (d differ) diff(key interface{}, before, after optimus.Row) optimus.Row {
+	switch {
+	case before == nil:
+		return optimus.Row{"change": DiffAdded, "key": key, "after": after}
+	case after == nil:
+		return optimus.Row{"change": DiffRemoved, "key": key, "before": before}
+	}
+	fields := map[string]optimus.Row{}
+	compare := func(field string) {
+		if _, done := fields[field]; done || d.ignore[field] {
+			return
+		}
+		if b, a := before[field], after[field]; !d.equal(field, b, a) {
+			fields[field] = optimus.Row{"before": b, "after": a}
+		}
+	}
+	for field := range before {
+		compare(field)
+	}
+	for field := range after {
+		compare(field)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return optimus.Row{"change": DiffChanged, "key": key, "before": before, "after": after, "fields": fields}
+}
+
+// Diff returns a TransformFunc that compares the Rows in the table with the Rows in a previous
+// snapshot of it, matching Rows by their key. It sends a Row for every change:
+//
+//	{"change": "added", "key": key, "after": row}
+//	{"change": "removed", "key": key, "before": row}
+//	{"change": "changed", "key": key, "before": row, "after": row, "fields": fields}
+//
+// where fields maps each field that changed to a Row of its before and after values, e.g.
+// map[string]optimus.Row{"grade": {"before": "9", "after": "10"}}. Rows that didn't change aren't
+// sent. Keys are expected to be unique, and Rows whose key is nil are always added or removed.
+// Like Pair, which it uses, the whole previous table is held in memory. See SortedDiff for tables
+// that are too big for that.
+func Diff(previous optimus.Table, key RowIdentifier, options DiffOptions) optimus.TransformFunc {
+	d := newDiffer(options)
+	pair := Pair(previous, key, key, OuterJoin)
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		pairs := make(chan optimus.Row)
+		errs := make(chan error, 1)
+		go func() {
+			defer close(pairs)
+			errs <- optimus.Recover(func() error {
+				return pair(in, pairs)
+			})
+		}()
+		for pair := range pairs {
+			after, _ := pair["left"].(optimus.Row)
+			before, _ := pair["right"].(optimus.Row)
+			row := after
+			if row == nil {
+				row = before
+			}
+			id, err := key(row)
+			if err != nil {
+				for range pairs {
+				}
+				<-errs
+				return err
+			}
+			if change := d.diff(id, before, after); change != nil {
+				out <- change
+			}
+		}
+		return <-errs
+	}
+}
+
+// compareKeys compares two keys from SortedDiff, which must both be ints, float64s or strings.
+func compareKeys(a, b interface{}) (int, error) {
+	switch a := a.(type) {
+	case int:
+		if b, ok := b.(int); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case float64:
+		if b, ok := b.(float64); ok {
+			return cmp.Compare(a, b), nil
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return cmp.Compare(a, b), nil
+		}
+	default:
+		return 0, fmt.Errorf("SortedDiff keys must be an int, float64 or string, got %#v", a)
+	}
+	return 0, fmt.Errorf("SortedDiff keys must all be the same type, got %#v and %#v", a, b)
+}
+
+// sortedKeys reads Rows and their keys from a channel, checking that they're in ascending order.
+type sortedKeys struct {
+	name string
+	rows <-chan optimus.Row
+	key  RowIdentifier
+	row  optimus.Row
+	id   interface{}
+	ok   bool
+}
+
+func (s *sortedKeys) next() error {
+	var row optimus.Row
+	if row, s.ok = <-s.rows; !s.ok {
+		return nil
+	}
+	id, err := s.key(row)
+	if err != nil {
+		return err
+	}
+	if s.row != nil {
+		if cmp, err := compareKeys(s.id, id); err != nil {
+			return err
+		} else if cmp > 0 {
+			return fmt.Errorf("SortedDiff's %s table isn't sorted: %#v came after %#v", s.name, id, s.id)
+		}
+	}
+	s.row, s.id = row, id
+	return nil
+}
+
+// SortedDiff returns a TransformFunc that sends the same changes as Diff, but only holds one Row
+// from each table in memory. Both the table and the previous table must already be sorted by key,
+// in ascending order, and keys must be ints, float64s or strings. Changes are sent in key order.
+func SortedDiff(previous optimus.Table, key RowIdentifier, options DiffOptions) optimus.TransformFunc {
+	d := newDiffer(options)
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		defer func() {
+			previous.Stop()
+			for range previous.Rows() {
+				// Drain the previous table in case we returned early
+			}
+		}()
+		before := &sortedKeys{name: "previous", rows: previous.Rows(), key: key}
+		after := &sortedKeys{name: "current", rows: in, key: key}
+		if err := before.next(); err != nil {
+			return err
+		}
+		if err := after.next(); err != nil {
+			return err
+		}
+		for before.ok || after.ok {
+			cmp := 0
+			switch {
+			case !before.ok:
+				cmp = 1
+			case !after.ok:
+				cmp = -1
+			default:
+				var err error
+				if cmp, err = compareKeys(before.id, after.id); err != nil {
+					return err
+				}
+			}
+			var change optimus.Row
+			var err error
+			switch {
+			case cmp < 0:
+				change = d.diff(before.id, before.row, nil)
+				err = before.next()
+			case cmp > 0:
+				change = d.diff(after.id, nil, after.row)
+				err = after.next()
+			default:
+				change = d.diff(after.id, before.row, after.row)
+				if err = before.next(); err == nil {
+					err = after.next()
+				}
+			}
+			if change != nil {
+				out <- change
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return previous.Err()
+	}
+}
@@ -0,0 +1,106 @@
+package transforms
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	errorSource "github.com/Clever/optimus/v4/sources/error"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+var yesterday = []optimus.Row{
+	{"id": "1", "name": "Ada", "grade": "9", "synced_at": "mon"},
+	{"id": "2", "name": "Grace", "grade": "10", "synced_at": "mon"},
+	{"id": "3", "name": "Alan", "grade": "11", "synced_at": "mon"},
+}
+
+var today = []optimus.Row{
+	{"id": "1", "name": "Ada", "grade": "10", "synced_at": "tue"},
+	{"id": "3", "name": "alan", "grade": "11", "synced_at": "tue"},
+	{"id": "4", "name": "Edsger", "grade": "12", "synced_at": "tue"},
+}
+
+var diffTests = []struct {
+	desc     string
+	options  DiffOptions
+	expected []optimus.Row
+}{
+	{
+		desc:    "ignoring synced_at",
+		options: DiffOptions{Ignore: []string{"synced_at"}},
+		expected: []optimus.Row{
+			{"change": DiffChanged, "key": "1", "before": yesterday[0], "after": today[0],
+				"fields": map[string]optimus.Row{"grade": {"before": "9", "after": "10"}}},
+			{"change": DiffRemoved, "key": "2", "before": yesterday[1]},
+			{"change": DiffChanged, "key": "3", "before": yesterday[2], "after": today[1],
+				"fields": map[string]optimus.Row{"name": {"before": "Alan", "after": "alan"}}},
+			{"change": DiffAdded, "key": "4", "after": today[2]},
+		},
+	},
+	{
+		desc: "case-insensitive names",
+		options: DiffOptions{
+			Ignore: []string{"synced_at"},
+			Equal: func(field string, before, after interface{}) bool {
+				if field == "name" {
+					return strings.EqualFold(before.(string), after.(string))
+				}
+				return before == after
+			},
+		},
+		expected: []optimus.Row{
+			{"change": DiffChanged, "key": "1", "before": yesterday[0], "after": today[0],
+				"fields": map[string]optimus.Row{"grade": {"before": "9", "after": "10"}}},
+			{"change": DiffRemoved, "key": "2", "before": yesterday[1]},
+			{"change": DiffAdded, "key": "4", "after": today[2]},
+		},
+	},
+}
+
+func TestDiff(t *testing.T) {
+	for _, diffTest := range diffTests {
+		table := optimus.Transform(slice.New(today), Diff(slice.New(yesterday), KeyIdentifier("id"), diffTest.options))
+		// Diff outputs the removed Rows last, so only SortedDiff's output is in order.
+		if !tests.CompareRows(t, diffTest.expected, tests.GetRows(table), tests.Comparison{Mode: tests.Keyed, Key: KeyIdentifier("key")}) {
+			t.Errorf("%s failed", diffTest.desc)
+		}
+		assert.Nil(t, table.Err())
+
+		table = optimus.Transform(slice.New(today), SortedDiff(slice.New(yesterday), KeyIdentifier("id"), diffTest.options))
+		assert.Equal(t, diffTest.expected, tests.GetRows(table), diffTest.desc)
+		assert.Nil(t, table.Err())
+	}
+}
+
+func TestDiffNothingChanged(t *testing.T) {
+	table := optimus.Transform(slice.New(today), Diff(slice.New(today), KeyIdentifier("id"), DiffOptions{}))
+	tests.Consumed(t, table)
+	assert.Nil(t, table.Err())
+
+	table = optimus.Transform(slice.New(today), SortedDiff(slice.New(today), KeyIdentifier("id"), DiffOptions{}))
+	tests.Consumed(t, table)
+	assert.Nil(t, table.Err())
+}
+
+func TestSortedDiffErrors(t *testing.T) {
+	unsorted := []optimus.Row{today[2], today[0]}
+	table := optimus.Transform(slice.New(unsorted), SortedDiff(slice.New(yesterday), KeyIdentifier("id"), DiffOptions{}))
+	tests.GetRows(table)
+	assert.EqualError(t, table.Err(), `SortedDiff's current table isn't sorted: "1" came after "4"`)
+
+	table = optimus.Transform(slice.New(today), SortedDiff(slice.New([]optimus.Row{{"id": 1}}), KeyIdentifier("id"), DiffOptions{}))
+	tests.GetRows(table)
+	assert.EqualError(t, table.Err(), `SortedDiff keys must all be the same type, got 1 and "1"`)
+
+	table = optimus.Transform(slice.New(today), SortedDiff(errorSource.New(fmt.Errorf("garbage error")), KeyIdentifier("id"), DiffOptions{}))
+	tests.GetRows(table)
+	assert.EqualError(t, table.Err(), "garbage error")
+
+	table = optimus.Transform(slice.New(today), Diff(errorSource.New(fmt.Errorf("garbage error")), KeyIdentifier("id"), DiffOptions{}))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "garbage error")
+}
@@ -4,7 +4,9 @@ Package transforms provides a set of transformation functions that can be applie
 For backwards-compatibility, there is a Pair transform and a Join transform.
 
 Join is the same as Pair, except that it overwrites the fields in the left row with the fields
-from the right row.
+from the right row. JoinBy also supports semi and anti joins, which filter the left table by
+whether it has a match in the right table, and takes a MergeFunc to control how matching rows
+are combined.
 
 In later versions, the Join transform will be removed and Pair will be renamed Join.
 The JoinType struct will also be removed in favor of the LeftJoin, OuterJoin, etc. functions
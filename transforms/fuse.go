@@ -0,0 +1,105 @@
+package transforms
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// RowStep is a row-wise transform: it returns the transformed Row, and whether to keep it. Fuse
+// runs a series of RowSteps as a single TransformFunc.
+type RowStep func(optimus.Row) (optimus.Row, bool, error)
+
+// Fuse returns a TransformFunc that runs every Row through each of the steps in turn, and sends
+// it if all of them keep it. It's equivalent to applying the Map, Select, Each and Fieldmap
+// transforms that the steps correspond to one after another, but runs in a single stage of the
+// pipeline, so the Rows aren't handed off between goroutines in between.
+func Fuse(steps ...RowStep) optimus.TransformFunc {
+	return TableTransform(func(row optimus.Row, out chan<- optimus.Row) error {
+		row, keep, err := runSteps(steps, row)
+		if err != nil || !keep {
+			return err
+		}
+		out <- row
+		return nil
+	})
+}
+
+// runSteps runs a Row through each of the steps, until one of them doesn't keep it.
+func runSteps(steps []RowStep, row optimus.Row) (optimus.Row, bool, error) {
+	for _, step := range steps {
+		var keep bool
+		var err error
+		if row, keep, err = step(row); err != nil || !keep {
+			return row, false, err
+		}
+	}
+	return row, true, nil
+}
+
+// MapStep returns a RowStep that transforms every Row with the given function, like Map.
+func MapStep(transform func(optimus.Row) (optimus.Row, error)) RowStep {
+	return func(row optimus.Row) (optimus.Row, bool, error) {
+		row, err := transform(row)
+		return row, err == nil, err
+	}
+}
+
+// SelectStep returns a RowStep that removes any Rows that don't pass the filter, like Select.
+func SelectStep(filter func(optimus.Row) (bool, error)) RowStep {
+	return func(row optimus.Row) (optimus.Row, bool, error) {
+		pass, err := filter(row)
+		return row, pass && err == nil, err
+	}
+}
+
+// EachStep returns a RowStep that calls the given function on every Row, like Each.
+func EachStep(fn func(optimus.Row) error) RowStep {
+	return func(row optimus.Row) (optimus.Row, bool, error) {
+		err := fn(row)
+		return row, err == nil, err
+	}
+}
+
+// FieldmapStep returns a RowStep that applies a field mapping to every Row, like Fieldmap.
+func FieldmapStep(mappings map[string][]string) RowStep {
+	return func(row optimus.Row) (optimus.Row, bool, error) {
+		newRow := optimus.Row{}
+		for key, vals := range mappings {
+			for _, val := range vals {
+				if oldRowVal, ok := GetPath(row, key); ok {
+					newRow[val] = oldRowVal
+				}
+			}
+		}
+		return newRow, true, nil
+	}
+}
+
+// ValuemapStep returns a RowStep that applies a value mapping to every Row, like Valuemap.
+func ValuemapStep(mappings map[string]map[interface{}]interface{}) RowStep {
+	return func(row optimus.Row) (optimus.Row, bool, error) {
+		newRow := optimus.Row{}
+		for key, val := range row {
+			if mappings[key] == nil || mappings[key][val] == nil {
+				newRow[key] = val
+				continue
+			}
+			newRow[key] = mappings[key][val]
+		}
+		for path, mapping := range mappings {
+			if _, ok := row[path]; ok || !strings.Contains(path, PathSeparator) {
+				continue
+			}
+			val, ok := GetPath(row, path)
+			if !ok || val == nil || !reflect.TypeOf(val).Comparable() || mapping[val] == nil {
+				continue
+			}
+			if err := SetPath(newRow, path, mapping[val]); err != nil {
+				return nil, false, err
+			}
+		}
+		return newRow, true, nil
+	}
+}
@@ -0,0 +1,67 @@
+package transforms
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/infinite"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuse(t *testing.T) {
+	double := func(row optimus.Row) (optimus.Row, error) {
+		return optimus.Row{"i": row["i"].(int) * 2, "name": row["name"]}, nil
+	}
+	over := func(n int) func(optimus.Row) (bool, error) {
+		return func(row optimus.Row) (bool, error) { return row["i"].(int) > n, nil }
+	}
+	mappings := map[string][]string{"i": {"j"}}
+	seen := 0
+	each := func(optimus.Row) error {
+		seen++
+		return nil
+	}
+	source := func() optimus.Table {
+		return slice.New([]optimus.Row{{"i": 1, "name": "a"}, {"i": 2, "name": "b"}, {"i": 3, "name": "c"}})
+	}
+
+	expected := source()
+	for _, transform := range []optimus.TransformFunc{Map(double), Select(over(2)), Each(each), Fieldmap(mappings)} {
+		expected = optimus.Transform(expected, transform)
+	}
+	expectedRows := tests.GetRows(expected)
+	assert.Equal(t, []optimus.Row{{"j": 4}, {"j": 6}}, expectedRows)
+
+	seen = 0
+	actual := optimus.Transform(source(), Fuse(
+		MapStep(double), SelectStep(over(2)), EachStep(each), FieldmapStep(mappings)))
+	assert.Equal(t, expectedRows, tests.GetRows(actual))
+	assert.Nil(t, actual.Err())
+	// The Rows that Select removes don't reach the steps after it.
+	assert.Equal(t, 2, seen)
+}
+
+func TestFuseNothing(t *testing.T) {
+	table := optimus.Transform(defaultSource(), Fuse())
+	assert.Equal(t, defaultInput(), tests.GetRows(table))
+	assert.Nil(t, table.Err())
+}
+
+func TestFuseError(t *testing.T) {
+	in := infinite.New()
+	calls := 0
+	out := optimus.Transform(in, Fuse(
+		MapStep(func(optimus.Row) (optimus.Row, error) { return nil, errors.New("failed") }),
+		EachStep(func(optimus.Row) error {
+			calls++
+			return nil
+		}),
+	))
+	tests.HasRows(t, out, 0)
+	assert.EqualError(t, out.Err(), "failed")
+	assert.Equal(t, 0, calls)
+	tests.Consumed(t, in)
+}
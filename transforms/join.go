@@ -0,0 +1,141 @@
+package transforms
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// MergeFunc combines a row from the left table with a row from the right table that it was
+// joined with. Either Row may be nil if there was no corresponding row on that side.
+type MergeFunc func(left, right optimus.Row) (optimus.Row, error)
+
+var (
+	// RightWins merges the rows, keeping the right row's value for fields that are in both.
+	RightWins MergeFunc = func(left, right optimus.Row) (optimus.Row, error) {
+		return mergeRows(left, right), nil
+	}
+	// LeftWins merges the rows, keeping the left row's value for fields that are in both.
+	LeftWins MergeFunc = func(left, right optimus.Row) (optimus.Row, error) {
+		return mergeRows(right, left), nil
+	}
+	// ErrorOnConflict merges the rows, returning an error if a field is in both rows with
+	// different values.
+	ErrorOnConflict MergeFunc = func(left, right optimus.Row) (optimus.Row, error) {
+		for _, field := range conflicts(left, right) {
+			if !reflect.DeepEqual(left[field], right[field]) {
+				return nil, fmt.Errorf("conflicting values for field '%s' when merging: %#v and %#v",
+					field, left[field], right[field])
+			}
+		}
+		return mergeRows(left, right), nil
+	}
+)
+
+// conflicts returns the fields that are in both Rows, in sorted order.
+func conflicts(left, right optimus.Row) []string {
+	fields := []string{}
+	for field := range left {
+		if _, ok := right[field]; ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func prefixed(row optimus.Row, prefix string, only map[string]bool) optimus.Row {
+	out := optimus.Row{}
+	for k, v := range row {
+		if only == nil || only[k] {
+			k = prefix + k
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// PrefixColumns returns a MergeFunc that prefixes every field of the left row with leftPrefix and
+// every field of the right row with rightPrefix before merging them.
+func PrefixColumns(leftPrefix, rightPrefix string) MergeFunc {
+	return func(left, right optimus.Row) (optimus.Row, error) {
+		return ErrorOnConflict(prefixed(left, leftPrefix, nil), prefixed(right, rightPrefix, nil))
+	}
+}
+
+// PrefixConflicts returns a MergeFunc that merges the rows, prefixing the fields that are in
+// both rows with leftPrefix and rightPrefix so that both values are kept.
+func PrefixConflicts(leftPrefix, rightPrefix string) MergeFunc {
+	return func(left, right optimus.Row) (optimus.Row, error) {
+		both := map[string]bool{}
+		for _, field := range conflicts(left, right) {
+			both[field] = true
+		}
+		return ErrorOnConflict(prefixed(left, leftPrefix, both), prefixed(right, rightPrefix, both))
+	}
+}
+
+func drain(c <-chan optimus.Row) {
+	for range c {
+		// Drain everything left in the channel
+	}
+}
+
+// filterByMatch returns a TransformFunc that keeps the rows whose identifier does (or doesn't)
+// match the identifier of a row in the right table. Like Pair, nil identifiers never match.
+func filterByMatch(rightTable optimus.Table, leftID, rightID RowIdentifier, matched bool) optimus.TransformFunc {
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		ids := map[interface{}]bool{}
+		for row := range rightTable.Rows() {
+			id, err := rightID(row)
+			if err != nil {
+				rightTable.Stop()
+				drain(rightTable.Rows())
+				return err
+			}
+			if id != nil {
+				ids[id] = true
+			}
+		}
+		if err := rightTable.Err(); err != nil {
+			return err
+		}
+		return Select(func(row optimus.Row) (bool, error) {
+			id, err := leftID(row)
+			if err != nil {
+				return false, err
+			}
+			return (id != nil && ids[id]) == matched, nil
+		})(in, out)
+	}
+}
+
+// CrossJoin returns a TransformFunc that joins every Row with every row in another table,
+// combining them with the MergeFunc. A nil MergeFunc means RightWins. The other table is held in
+// memory.
+func CrossJoin(rightTable optimus.Table, merge MergeFunc) optimus.TransformFunc {
+	if merge == nil {
+		merge = RightWins
+	}
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		right := []optimus.Row{}
+		for row := range rightTable.Rows() {
+			right = append(right, row)
+		}
+		if err := rightTable.Err(); err != nil {
+			return err
+		}
+		return TableTransform(func(left optimus.Row, out chan<- optimus.Row) error {
+			for _, r := range right {
+				merged, err := merge(left, r)
+				if err != nil {
+					return err
+				}
+				out <- merged
+			}
+			return nil
+		})(in, out)
+	}
+}
@@ -0,0 +1,141 @@
+package transforms
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	errorTable "github.com/Clever/optimus/v4/sources/error"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+var roster = func() []optimus.Row {
+	return []optimus.Row{
+		{"id": "1", "name": "Ada"},
+		{"id": "2", "name": "Grace"},
+		{"id": "3", "name": "Alan"},
+		{"name": "Edsger"},
+	}
+}
+
+var enrollments = func() []optimus.Row {
+	return []optimus.Row{
+		{"student": "1", "name": "Math"},
+		{"student": "1", "name": "Science"},
+		{"student": "3", "name": "Art"},
+		{"student": "5", "name": "Music"},
+	}
+}
+
+var joinByTests = []struct {
+	desc   string
+	join   joinType
+	merge  MergeFunc
+	golden string
+}{
+	{desc: "semi", join: JoinType.Semi, golden: "join_by/semi.ndjson"},
+	{desc: "anti", join: JoinType.Anti, golden: "join_by/anti.ndjson"},
+	{
+		desc:   "right, with prefixed conflicts",
+		join:   JoinType.Right,
+		merge:  PrefixConflicts("student_", "class_"),
+		golden: "join_by/right_prefixed_conflicts.ndjson",
+	},
+	{desc: "outer, left wins", join: JoinType.Outer, merge: LeftWins, golden: "join_by/outer_left_wins.ndjson"},
+	{
+		desc:   "inner, prefixed columns",
+		join:   JoinType.Inner,
+		merge:  PrefixColumns("s.", "e."),
+		golden: "join_by/inner_prefixed_columns.ndjson",
+	},
+}
+
+func TestJoinBy(t *testing.T) {
+	for _, joinByTest := range joinByTests {
+		table := optimus.Transform(slice.New(roster()), JoinBy(slice.New(enrollments()),
+			KeyIdentifier("id"), KeyIdentifier("student"), joinByTest.join, joinByTest.merge))
+		if !tests.GoldenNDJSON(t, table, joinByTest.golden) {
+			t.Errorf("%s failed", joinByTest.desc)
+		}
+	}
+}
+
+func TestJoinRightAndOuter(t *testing.T) {
+	table := optimus.Transform(slice.New(roster()), Join(slice.New(enrollments()), "id", "student", JoinType.Right))
+	assert.Equal(t, []optimus.Row{
+		{"id": "1", "name": "Math", "student": "1"},
+		{"id": "1", "name": "Science", "student": "1"},
+		{"id": "3", "name": "Art", "student": "3"},
+		{"name": "Music", "student": "5"},
+	}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+
+	table = optimus.Transform(slice.New(roster()), Join(slice.New(enrollments()), "id", "student", JoinType.Outer))
+	assert.Equal(t, 6, len(tests.GetRows(table)))
+	assert.Nil(t, table.Err())
+}
+
+func TestMergeFuncs(t *testing.T) {
+	left := optimus.Row{"a": 1, "b": 2}
+	right := optimus.Row{"b": 3, "c": 4}
+
+	merged, err := RightWins(left, right)
+	assert.Nil(t, err)
+	assert.Equal(t, optimus.Row{"a": 1, "b": 3, "c": 4}, merged)
+
+	merged, err = LeftWins(left, right)
+	assert.Nil(t, err)
+	assert.Equal(t, optimus.Row{"a": 1, "b": 2, "c": 4}, merged)
+
+	merged, err = ErrorOnConflict(left, optimus.Row{"b": 2, "c": 4})
+	assert.Nil(t, err)
+	assert.Equal(t, optimus.Row{"a": 1, "b": 2, "c": 4}, merged)
+	_, err = ErrorOnConflict(left, right)
+	assert.EqualError(t, err, "conflicting values for field 'b' when merging: 2 and 3")
+
+	merged, err = PrefixConflicts("l_", "r_")(left, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, left, merged)
+
+	// Prefixing can itself cause a conflict.
+	_, err = PrefixColumns("", "x")(optimus.Row{"xa": 1}, optimus.Row{"a": 2})
+	assert.EqualError(t, err, "conflicting values for field 'xa' when merging: 1 and 2")
+}
+
+func TestCrossJoin(t *testing.T) {
+	sizes := slice.New([]optimus.Row{{"size": "S"}, {"size": "L"}})
+	colors := slice.New([]optimus.Row{{"color": "red"}, {"color": "blue"}})
+	table := optimus.Transform(sizes, CrossJoin(colors, nil))
+	assert.Equal(t, []optimus.Row{
+		{"size": "S", "color": "red"},
+		{"size": "S", "color": "blue"},
+		{"size": "L", "color": "red"},
+		{"size": "L", "color": "blue"},
+	}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+
+	table = optimus.Transform(slice.New(roster()), CrossJoin(slice.New(enrollments()), ErrorOnConflict))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), `conflicting values for field 'name' when merging: "Ada" and "Math"`)
+}
+
+func TestJoinByErrors(t *testing.T) {
+	for _, join := range []joinType{JoinType.Semi, JoinType.Anti} {
+		right := errorTable.New(fmt.Errorf("garbage error"))
+		table := optimus.Transform(slice.New(roster()), JoinBy(right, KeyIdentifier("id"), KeyIdentifier("student"), join, nil))
+		tests.Consumed(t, table)
+		tests.Consumed(t, right)
+		assert.EqualError(t, table.Err(), "garbage error")
+	}
+
+	table := optimus.Transform(slice.New(roster()), CrossJoin(errorTable.New(fmt.Errorf("garbage error")), nil))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "garbage error")
+
+	table = optimus.Transform(slice.New(roster()), JoinBy(slice.New(enrollments()),
+		KeyIdentifier("id"), KeyIdentifier("student"), JoinType.Inner, ErrorOnConflict))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), `conflicting values for field 'name' when merging: "Ada" and "Math"`)
+}
@@ -0,0 +1,125 @@
+package transforms
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// Limit returns a TransformFunc that sends the first n Rows and drops the rest. It returns as soon
+// as it has sent n Rows, which Stops the upstream Tables so they don't read any further.
+func Limit(n int) optimus.TransformFunc {
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		if n <= 0 {
+			return nil
+		}
+		sent := 0
+		for row := range in {
+			out <- row
+			if sent++; sent == n {
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+// Head returns a TransformFunc that sends the first n Rows and drops the rest. It's the same as
+// Limit.
+func Head(n int) optimus.TransformFunc {
+	return Limit(n)
+}
+
+// Skip returns a TransformFunc that drops the first n Rows and sends the rest.
+func Skip(n int) optimus.TransformFunc {
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		skipped := 0
+		for row := range in {
+			if skipped < n {
+				skipped++
+				continue
+			}
+			out <- row
+		}
+		return nil
+	}
+}
+
+// Tail returns a TransformFunc that sends the last n Rows, in order. At most n Rows are kept in
+// memory.
+func Tail(n int) optimus.TransformFunc {
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		if n <= 0 {
+			for range in {
+			}
+			return nil
+		}
+		// last is a ring buffer of the last n Rows, where next is the index of the oldest one once
+		// it's full.
+		last := make([]optimus.Row, 0, n)
+		next := 0
+		for row := range in {
+			if len(last) < n {
+				last = append(last, row)
+				continue
+			}
+			last[next] = row
+			next = (next + 1) % n
+		}
+		for i := range last {
+			out <- last[(next+i)%len(last)]
+		}
+		return nil
+	}
+}
+
+// ReservoirSample returns a TransformFunc that sends a uniformly random sample of n Rows, in the
+// order they were received. It keeps at most n Rows in memory. The same seed and input always
+// produce the same sample.
+func ReservoirSample(n int, seed int64) optimus.TransformFunc {
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		if n <= 0 {
+			for range in {
+			}
+			return nil
+		}
+		rnd := rand.New(rand.NewSource(seed))
+		type sampled struct {
+			index int
+			row   optimus.Row
+		}
+		reservoir := make([]sampled, 0, n)
+		seen := 0
+		for row := range in {
+			if len(reservoir) < n {
+				reservoir = append(reservoir, sampled{seen, row})
+			} else if j := rnd.Intn(seen + 1); j < n {
+				reservoir[j] = sampled{seen, row}
+			}
+			seen++
+		}
+		// Replacements don't preserve order, so put the sample back into input order.
+		sort.Slice(reservoir, func(i, j int) bool { return reservoir[i].index < reservoir[j].index })
+		for _, s := range reservoir {
+			out <- s.row
+		}
+		return nil
+	}
+}
+
+// BernoulliSample returns a TransformFunc that independently keeps each Row with probability p.
+// Unlike ReservoirSample, it streams and keeps nothing in memory, but the number of Rows sent
+// varies. The same seed and input always produce the same sample.
+func BernoulliSample(p float64, seed int64) optimus.TransformFunc {
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("BernoulliSample's probability must be between 0 and 1, got %v", p)
+		}
+		rnd := rand.New(rand.NewSource(seed))
+		return Select(func(optimus.Row) (bool, error) {
+			return rnd.Float64() < p, nil
+		})(in, out)
+	}
+}
@@ -0,0 +1,79 @@
+package transforms
+
+import (
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/infinite"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+func numberedRows(n int) []optimus.Row {
+	rows := []optimus.Row{}
+	for i := 0; i < n; i++ {
+		rows = append(rows, optimus.Row{"i": i})
+	}
+	return rows
+}
+
+var limitTests = []struct {
+	desc      string
+	transform optimus.TransformFunc
+	expected  []optimus.Row
+}{
+	{"limit", Limit(2), numberedRows(2)},
+	{"limit zero", Limit(0), numberedRows(0)},
+	{"limit more than input", Limit(10), numberedRows(5)},
+	{"head", Head(2), numberedRows(2)},
+	{"skip", Skip(3), numberedRows(5)[3:]},
+	{"skip more than input", Skip(10), numberedRows(0)},
+	{"tail", Tail(2), numberedRows(5)[3:]},
+	{"tail zero", Tail(0), numberedRows(0)},
+	{"tail more than input", Tail(10), numberedRows(5)},
+	{"reservoir sample more than input", ReservoirSample(10, 1), numberedRows(5)},
+	{"bernoulli sample everything", BernoulliSample(1, 1), numberedRows(5)},
+	{"bernoulli sample nothing", BernoulliSample(0, 1), numberedRows(0)},
+}
+
+func TestLimitSkipTail(t *testing.T) {
+	for _, limitTest := range limitTests {
+		table := optimus.Transform(slice.New(numberedRows(5)), limitTest.transform)
+		assert.Equal(t, limitTest.expected, tests.GetRows(table), limitTest.desc)
+		assert.Nil(t, table.Err(), limitTest.desc)
+	}
+}
+
+// TestLimitStopsUpstream tests that Limit stops its source, even when the source would never
+// finish on its own.
+func TestLimitStopsUpstream(t *testing.T) {
+	in := infinite.New()
+	out := optimus.Transform(in, Each(func(optimus.Row) error { return nil }))
+	out = optimus.Transform(out, Limit(3))
+	tests.HasRows(t, out, 3)
+	assert.Nil(t, out.Err())
+	tests.Consumed(t, in)
+}
+
+func TestSamplesAreReproducible(t *testing.T) {
+	for _, sample := range []func() optimus.TransformFunc{
+		func() optimus.TransformFunc { return ReservoirSample(10, 42) },
+		func() optimus.TransformFunc { return BernoulliSample(0.1, 42) },
+	} {
+		first := tests.GetRows(optimus.Transform(slice.New(numberedRows(100)), sample()))
+		second := tests.GetRows(optimus.Transform(slice.New(numberedRows(100)), sample()))
+		assert.Equal(t, first, second)
+		assert.NotEqual(t, numberedRows(len(first)), first, "expected a random sample")
+		for i := 1; i < len(first); i++ {
+			assert.True(t, first[i-1]["i"].(int) < first[i]["i"].(int), "expected input order")
+		}
+	}
+	assert.Len(t, tests.GetRows(optimus.Transform(slice.New(numberedRows(100)), ReservoirSample(10, 42))), 10)
+}
+
+func TestBernoulliSampleError(t *testing.T) {
+	table := optimus.Transform(slice.New(numberedRows(5)), BernoulliSample(2, 1))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "BernoulliSample's probability must be between 0 and 1, got 2")
+}
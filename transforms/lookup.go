@@ -0,0 +1,258 @@
+package transforms
+
+import (
+	"container/list"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// LookupConfig configures a Lookup transform. Either Fetch or FetchMany must be set.
+type LookupConfig struct {
+	// Key identifies the key to look up for each Row. It must return comparable values. Rows
+	// whose key is nil aren't looked up.
+	Key RowIdentifier
+	// Fetch returns the Row for a single key, or nil if there isn't one.
+	Fetch func(key interface{}) (optimus.Row, error)
+	// FetchMany returns the Rows for up to BatchSize keys at once. Keys missing from the result
+	// don't have a Row. If it's set, it's used instead of Fetch.
+	FetchMany func(keys []interface{}) (map[interface{}]optimus.Row, error)
+	// BatchSize is how many Rows are held so that their keys can be fetched together by FetchMany.
+	// Zero means 100. It isn't used by Fetch.
+	BatchSize int
+	// Merge combines a Row with the Row that was fetched for it, which is nil if there wasn't one.
+	// Nil means that the fetched Row's fields overwrite the Row's fields, like in Join.
+	Merge func(row, fetched optimus.Row) (optimus.Row, error)
+	// CacheSize is how many keys' Rows are cached. When the cache is full, the least recently
+	// used key is evicted. Zero means nothing is cached.
+	CacheSize int
+	// TTL is how long a cached Row is used for. Zero means until it's evicted.
+	TTL time.Duration
+	// Clock is used to expire cached Rows. Nil means SystemClock.
+	Clock Clock
+}
+
+// Lookup returns a TransformFunc that enriches every Row with a Row fetched by its key, e.g. from
+// a remote service. Unlike Pair and Join, nothing is fetched up front, only the keys that are
+// found in the Table.
+// Fetched Rows are cached, and a key that's already being fetched isn't fetched again; instead
+// its Row is shared. The cache is shared by every invocation of the returned TransformFunc, so it
+// can be wrapped in Concurrently to fetch several keys at once.
+func Lookup(config LookupConfig) optimus.TransformFunc {
+	l := &lookup{
+		config:   config,
+		cache:    newLRUCache(config.CacheSize, config.TTL, config.Clock),
+		inflight: map[interface{}]*lookupCall{},
+	}
+	if l.config.BatchSize < 1 {
+		l.config.BatchSize = 100
+	}
+	if l.config.Merge == nil {
+		l.config.Merge = func(row, fetched optimus.Row) (optimus.Row, error) {
+			return mergeRows(row, fetched), nil
+		}
+	}
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		if config.Fetch == nil && config.FetchMany == nil {
+			return fmt.Errorf("Lookup needs either Fetch or FetchMany")
+		}
+		batchSize := l.config.BatchSize
+		if config.FetchMany == nil {
+			batchSize = 1
+		}
+		batch := make([]optimus.Row, 0, batchSize)
+		for row := range in {
+			if batch = append(batch, row); len(batch) == batchSize {
+				if err := l.enrich(batch, out); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+		return l.enrich(batch, out)
+	}
+}
+
+type lookupCall struct {
+	done chan struct{}
+	row  optimus.Row
+	err  error
+}
+
+type lookup struct {
+	config   LookupConfig
+	m        sync.Mutex
+	cache    *lruCache
+	inflight map[interface{}]*lookupCall
+}
+
+// enrich fetches the Rows for a batch of Rows and sends the merged Rows, in order.
+func (l *lookup) enrich(rows []optimus.Row, out chan<- optimus.Row) error {
+	keys := make([]interface{}, len(rows))
+	for i, row := range rows {
+		key, err := l.config.Key(row)
+		if err != nil {
+			return err
+		}
+		if key != nil && !reflect.TypeOf(key).Comparable() {
+			return fmt.Errorf("Lookup keys must be comparable, got %#v", key)
+		}
+		keys[i] = key
+	}
+	fetched, err := l.get(keys)
+	if err != nil {
+		return err
+	}
+	for i, row := range rows {
+		var found optimus.Row
+		if keys[i] != nil {
+			found = fetched[keys[i]]
+		}
+		merged, err := l.config.Merge(row, found)
+		if err != nil {
+			return err
+		}
+		out <- merged
+	}
+	return nil
+}
+
+// get returns the Rows for the keys, from the cache, from calls that are already in flight, or
+// by fetching them.
+func (l *lookup) get(keys []interface{}) (map[interface{}]optimus.Row, error) {
+	found := map[interface{}]optimus.Row{}
+	waiting := map[interface{}]*lookupCall{}
+	calls := map[interface{}]*lookupCall{}
+	toFetch := []interface{}{}
+
+	l.m.Lock()
+	for _, key := range keys {
+		if _, seen := found[key]; seen || key == nil || waiting[key] != nil || calls[key] != nil {
+			continue
+		}
+		if row, ok := l.cache.get(key); ok {
+			found[key] = row
+		} else if call, ok := l.inflight[key]; ok {
+			waiting[key] = call
+		} else {
+			call := &lookupCall{done: make(chan struct{})}
+			l.inflight[key] = call
+			calls[key] = call
+			toFetch = append(toFetch, key)
+		}
+	}
+	l.m.Unlock()
+
+	if len(toFetch) > 0 {
+		l.fetch(toFetch, calls)
+	}
+	for key, call := range calls {
+		waiting[key] = call
+	}
+	for key, call := range waiting {
+		<-call.done
+		if call.err != nil {
+			return nil, call.err
+		}
+		found[key] = call.row
+	}
+	return found, nil
+}
+
+// fetch fetches the keys, and completes their calls. The calls are completed even if fetching
+// panics, with the panic as their error, so that nothing waits for them forever.
+func (l *lookup) fetch(keys []interface{}, calls map[interface{}]*lookupCall) {
+	err := optimus.Recover(func() error {
+		if l.config.FetchMany != nil {
+			rows, err := l.config.FetchMany(keys)
+			for _, key := range keys {
+				calls[key].row, calls[key].err = rows[key], err
+			}
+			return nil
+		}
+		for _, key := range keys {
+			calls[key].row, calls[key].err = l.config.Fetch(key)
+		}
+		return nil
+	})
+	l.m.Lock()
+	for _, key := range keys {
+		if err != nil {
+			calls[key].row, calls[key].err = nil, err
+		}
+		delete(l.inflight, key)
+		if calls[key].err == nil {
+			l.cache.add(key, calls[key].row)
+		}
+		close(calls[key].done)
+	}
+	l.m.Unlock()
+}
+
+type lruEntry struct {
+	key     interface{}
+	row     optimus.Row
+	expires time.Time
+}
+
+// lruCache is a cache of Rows that evicts the least recently used key when it's full. It isn't
+// safe to use concurrently.
+type lruCache struct {
+	size    int
+	ttl     time.Duration
+	clock   Clock
+	order   *list.List
+	entries map[interface{}]*list.Element
+}
+
+func newLRUCache(size int, ttl time.Duration, clock Clock) *lruCache {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &lruCache{
+		size:    size,
+		ttl:     ttl,
+		clock:   clock,
+		order:   list.New(),
+		entries: map[interface{}]*list.Element{},
+	}
+}
+
+func (c *lruCache) get(key interface{}) (optimus.Row, bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*lruEntry)
+	if c.ttl > 0 && !c.clock.Now().Before(entry.expires) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return entry.row, true
+}
+
+func (c *lruCache) add(key interface{}, row optimus.Row) {
+	if c.size < 1 {
+		return
+	}
+	entry := &lruEntry{key: key, row: row}
+	if c.ttl > 0 {
+		entry.expires = c.clock.Now().Add(c.ttl)
+	}
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
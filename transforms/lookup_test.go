@@ -0,0 +1,204 @@
+package transforms
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+var schools = map[interface{}]optimus.Row{
+	"s1": {"school_name": "Hogwarts"},
+	"s2": {"school_name": "Xavier's"},
+}
+
+var students = func() []optimus.Row {
+	return []optimus.Row{
+		{"name": "a", "school": "s1"},
+		{"name": "b", "school": "s2"},
+		{"name": "c", "school": "s1"},
+		{"name": "d", "school": "s3"},
+		{"name": "e"},
+	}
+}
+
+var enrichedStudents = []optimus.Row{
+	{"name": "a", "school": "s1", "school_name": "Hogwarts"},
+	{"name": "b", "school": "s2", "school_name": "Xavier's"},
+	{"name": "c", "school": "s1", "school_name": "Hogwarts"},
+	{"name": "d", "school": "s3"},
+	{"name": "e"},
+}
+
+func TestLookupFetch(t *testing.T) {
+	fetched := []interface{}{}
+	lookup := Lookup(LookupConfig{
+		Key: KeyIdentifier("school"),
+		Fetch: func(key interface{}) (optimus.Row, error) {
+			fetched = append(fetched, key)
+			return schools[key], nil
+		},
+		CacheSize: 10,
+	})
+	table := optimus.Transform(slice.New(students()), lookup)
+	assert.Equal(t, enrichedStudents, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+	assert.Equal(t, []interface{}{"s1", "s2", "s3"}, fetched)
+
+	// The cache is shared between runs.
+	table = optimus.Transform(slice.New(students()), lookup)
+	assert.Equal(t, enrichedStudents, tests.GetRows(table))
+	assert.Equal(t, []interface{}{"s1", "s2", "s3"}, fetched)
+}
+
+func TestLookupFetchMany(t *testing.T) {
+	fetched := [][]interface{}{}
+	table := optimus.Transform(slice.New(students()), Lookup(LookupConfig{
+		Key: KeyIdentifier("school"),
+		FetchMany: func(keys []interface{}) (map[interface{}]optimus.Row, error) {
+			fetched = append(fetched, keys)
+			found := map[interface{}]optimus.Row{}
+			for _, key := range keys {
+				if row, ok := schools[key]; ok {
+					found[key] = row
+				}
+			}
+			return found, nil
+		},
+		BatchSize: 3,
+		Merge: func(row, school optimus.Row) (optimus.Row, error) {
+			if school == nil {
+				return row, nil
+			}
+			return optimus.Row{"name": row["name"], "school_name": school["school_name"]}, nil
+		},
+	}))
+	assert.Equal(t, []optimus.Row{
+		{"name": "a", "school_name": "Hogwarts"},
+		{"name": "b", "school_name": "Xavier's"},
+		{"name": "c", "school_name": "Hogwarts"},
+		{"name": "d", "school": "s3"},
+		{"name": "e"},
+	}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+	// Without a cache, each batch fetches its own distinct keys.
+	assert.Equal(t, [][]interface{}{{"s1", "s2"}, {"s3"}}, fetched)
+}
+
+func TestLookupCacheEviction(t *testing.T) {
+	clock := &fakeClock{}
+	cache := newLRUCache(2, time.Minute, clock)
+	cache.add("a", optimus.Row{"a": 1})
+	cache.add("b", optimus.Row{"b": 1})
+	_, ok := cache.get("a")
+	assert.True(t, ok)
+	// b is the least recently used, so it's evicted.
+	cache.add("c", optimus.Row{"c": 1})
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	row, ok := cache.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, optimus.Row{"c": 1}, row)
+
+	clock.After(time.Minute)
+	_, ok = cache.get("a")
+	assert.False(t, ok, "expected a to expire")
+}
+
+// TestLookupCoalescing tests that concurrent lookups of the same key share one fetch, even
+// without a cache.
+func TestLookupCoalescing(t *testing.T) {
+	var m sync.Mutex
+	calls := 0
+	release := make(chan struct{})
+	lookup := Lookup(LookupConfig{
+		Key: KeyIdentifier("school"),
+		Fetch: func(key interface{}) (optimus.Row, error) {
+			m.Lock()
+			calls++
+			m.Unlock()
+			<-release
+			return schools[key], nil
+		},
+	})
+	input := []optimus.Row{}
+	for i := 0; i < 8; i++ {
+		input = append(input, optimus.Row{"school": "s1"})
+	}
+	table := optimus.Transform(slice.New(input), Concurrently(lookup, 8))
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(release)
+	}()
+	rows := tests.HasRows(t, table, 8)
+	assert.Nil(t, table.Err())
+	assert.Equal(t, optimus.Row{"school": "s1", "school_name": "Hogwarts"}, rows[0])
+	assert.Equal(t, 1, calls)
+}
+
+// TestLookupConcurrentFailures tests that the Rows that wait for a key that's being fetched fail
+// when fetching it fails or panics, instead of waiting forever.
+func TestLookupConcurrentFailures(t *testing.T) {
+	fetches := map[string]func(key interface{}) (optimus.Row, error){
+		"error": func(key interface{}) (optimus.Row, error) {
+			time.Sleep(10 * time.Millisecond)
+			return nil, errors.New("fetch error")
+		},
+		"panic": func(key interface{}) (optimus.Row, error) {
+			time.Sleep(10 * time.Millisecond)
+			panic("fetch panic")
+		},
+	}
+	for name, fetch := range fetches {
+		input := []optimus.Row{}
+		for i := 0; i < 50; i++ {
+			input = append(input, optimus.Row{"school": "s1"})
+		}
+		table := optimus.Transform(slice.New(input),
+			Concurrently(Lookup(LookupConfig{Key: KeyIdentifier("school"), Fetch: fetch}), 4))
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			tests.Consumed(t, table)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: the Table wasn't closed", name)
+		}
+		assert.Error(t, table.Err(), name)
+	}
+	var panicErr *optimus.PanicError
+	table := optimus.Transform(slice.New(students()), Lookup(LookupConfig{
+		Key: KeyIdentifier("school"), Fetch: fetches["panic"],
+	}))
+	tests.Consumed(t, table)
+	assert.True(t, errors.As(table.Err(), &panicErr))
+}
+
+func TestLookupErrors(t *testing.T) {
+	table := optimus.Transform(slice.New(students()), Lookup(LookupConfig{
+		Key: KeyIdentifier("school"),
+		Fetch: func(key interface{}) (optimus.Row, error) {
+			return nil, errors.New("fetch error")
+		},
+	}))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "fetch error")
+
+	table = optimus.Transform(slice.New(students()), Lookup(LookupConfig{Key: KeyIdentifier("school")}))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "Lookup needs either Fetch or FetchMany")
+
+	table = optimus.Transform(slice.New([]optimus.Row{{"school": []string{"s1"}}}), Lookup(LookupConfig{
+		Key:   KeyIdentifier("school"),
+		Fetch: func(key interface{}) (optimus.Row, error) { return nil, nil },
+	}))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), `Lookup keys must be comparable, got []string{"s1"}`)
+}
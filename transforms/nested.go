@@ -0,0 +1,185 @@
+package transforms
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// PathSeparator separates the keys of nested objects in a dotted path, e.g. "address.city".
+const PathSeparator = "."
+
+var mapType = reflect.TypeOf(map[string]interface{}{})
+
+// asMap returns the value as a map if it's a nested object. This includes optimus.Rows, the
+// map[string]interface{}s produced by encoding/json and named map types like bson.M.
+func asMap(val interface{}) (map[string]interface{}, bool) {
+	switch m := val.(type) {
+	case map[string]interface{}:
+		return m, true
+	case optimus.Row:
+		return m, true
+	case nil:
+		return nil, false
+	}
+	rv := reflect.ValueOf(val)
+	if rv.Kind() == reflect.Map && rv.Type().ConvertibleTo(mapType) {
+		return rv.Convert(mapType).Interface().(map[string]interface{}), true
+	}
+	return nil, false
+}
+
+// copyMap returns a shallow copy of a nested object, both as its original type and as a map that
+// shares its contents.
+func copyMap(val interface{}, m map[string]interface{}) (interface{}, map[string]interface{}) {
+	cp := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		cp[k] = v
+	}
+	return reflect.ValueOf(cp).Convert(reflect.TypeOf(val)).Interface(), cp
+}
+
+// GetPath returns the value at a dotted path in the Row, and whether it was found. If the Row has
+// a top-level key that is exactly the path, that key's value is returned. Otherwise each
+// component of the path is looked up in the nested object found for the previous one.
+func GetPath(row optimus.Row, path string) (interface{}, bool) {
+	if val, ok := row[path]; ok {
+		return val, true
+	}
+	var cur interface{} = map[string]interface{}(row)
+	for _, key := range strings.Split(path, PathSeparator) {
+		m, ok := asMap(cur)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// SetPath sets the value at a dotted path in the Row, creating nested objects as needed. If the
+// Row has a top-level key that is exactly the path, that key is set. Nested objects along the
+// path are copied rather than modified, so other Rows that share them are unaffected. It returns
+// an error if a component of the path already holds something other than a nested object.
+func SetPath(row optimus.Row, path string, value interface{}) error {
+	if _, ok := row[path]; ok || !strings.Contains(path, PathSeparator) {
+		row[path] = value
+		return nil
+	}
+	keys := strings.Split(path, PathSeparator)
+	parent := map[string]interface{}(row)
+	for i, key := range keys[:len(keys)-1] {
+		existing, ok := parent[key]
+		if !ok || existing == nil {
+			child := map[string]interface{}{}
+			parent[key] = child
+			parent = child
+			continue
+		}
+		m, ok := asMap(existing)
+		if !ok {
+			return fmt.Errorf("cannot set '%s': '%s' is not a nested object, had value: %#v",
+				path, strings.Join(keys[:i+1], PathSeparator), existing)
+		}
+		cp, child := copyMap(existing, m)
+		parent[key] = cp
+		parent = child
+	}
+	parent[keys[len(keys)-1]] = value
+	return nil
+}
+
+func flattenInto(flat optimus.Row, prefix string, m map[string]interface{}) error {
+	for key, val := range m {
+		if prefix != "" {
+			key = prefix + PathSeparator + key
+		}
+		if nested, ok := asMap(val); ok && len(nested) > 0 {
+			if err := flattenInto(flat, key, nested); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := flat[key]; ok {
+			return fmt.Errorf("flattening produced the key '%s' more than once", key)
+		}
+		flat[key] = val
+	}
+	return nil
+}
+
+// Flatten returns a TransformFunc that flattens nested objects into top-level dotted keys, e.g.
+// {"address": {"city": "SF"}} becomes {"address.city": "SF"}. Arrays and empty objects are left
+// as they are. It returns an error if two fields flatten to the same key.
+func Flatten() optimus.TransformFunc {
+	return Map(func(row optimus.Row) (optimus.Row, error) {
+		flat := optimus.Row{}
+		if err := flattenInto(flat, "", row); err != nil {
+			return nil, err
+		}
+		return flat, nil
+	})
+}
+
+// Unflatten returns a TransformFunc that turns dotted keys back into nested objects, e.g.
+// {"address.city": "SF"} becomes {"address": {"city": "SF"}}. It returns an error if a key is
+// both a value and the parent of a dotted key, e.g. {"a": 1, "a.b": 2}.
+func Unflatten() optimus.TransformFunc {
+	return Map(func(row optimus.Row) (optimus.Row, error) {
+		nested := optimus.Row{}
+		dotted := []string{}
+		for key, val := range row {
+			if strings.Contains(key, PathSeparator) {
+				dotted = append(dotted, key)
+				continue
+			}
+			nested[key] = val
+		}
+		// Sort the keys so that conflicts are reported the same way on every run.
+		sort.Strings(dotted)
+		for _, key := range dotted {
+			if _, ok := GetPath(nested, key); ok {
+				return nil, fmt.Errorf("cannot unflatten '%s': the key was already set", key)
+			}
+			if err := SetPath(nested, key, row[key]); err != nil {
+				return nil, err
+			}
+		}
+		return nested, nil
+	})
+}
+
+// Explode returns a TransformFunc that sends one Row per element of the array at the given
+// dotted path. Each output Row has all of the fields of the input Row, with the array replaced by
+// one of its elements. Rows whose array is empty are dropped, and Rows where the path is missing
+// or doesn't hold an array are sent unchanged.
+func Explode(path string) optimus.TransformFunc {
+	return TableTransform(func(row optimus.Row, out chan<- optimus.Row) error {
+		val, ok := GetPath(row, path)
+		rv := reflect.ValueOf(val)
+		if !ok || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+			out <- row
+			return nil
+		}
+		if _, isBytes := val.([]byte); isBytes {
+			out <- row
+			return nil
+		}
+		for i := 0; i < rv.Len(); i++ {
+			exploded := optimus.Row{}
+			for k, v := range row {
+				exploded[k] = v
+			}
+			if err := SetPath(exploded, path, rv.Index(i).Interface()); err != nil {
+				return err
+			}
+			out <- exploded
+		}
+		return nil
+	})
+}
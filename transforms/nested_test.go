@@ -0,0 +1,149 @@
+package transforms
+
+import (
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+type bsonM map[string]interface{}
+
+var nestedRow = func() optimus.Row {
+	return optimus.Row{
+		"name": "Ada",
+		"address": map[string]interface{}{
+			"city": "SF",
+			"geo":  bsonM{"lat": 37.7, "lng": -122.4},
+		},
+		"tags": []interface{}{"a", "b"},
+	}
+}
+
+func TestGetPath(t *testing.T) {
+	row := nestedRow()
+	row["literal.key"] = "literal"
+
+	val, ok := GetPath(row, "address.city")
+	assert.True(t, ok)
+	assert.Equal(t, "SF", val)
+
+	val, ok = GetPath(row, "address.geo.lat")
+	assert.True(t, ok)
+	assert.Equal(t, 37.7, val)
+
+	val, ok = GetPath(row, "literal.key")
+	assert.True(t, ok)
+	assert.Equal(t, "literal", val)
+
+	_, ok = GetPath(row, "address.zip")
+	assert.False(t, ok)
+	_, ok = GetPath(row, "name.first")
+	assert.False(t, ok)
+}
+
+func TestSetPathCopiesNestedObjects(t *testing.T) {
+	row := nestedRow()
+	cp := optimus.Row{}
+	for k, v := range row {
+		cp[k] = v
+	}
+	assert.Nil(t, SetPath(cp, "address.geo.lat", 0.0))
+	assert.Nil(t, SetPath(cp, "school.name", "Hogwarts"))
+	assert.EqualError(t, SetPath(cp, "name.first", "Ada"),
+		`cannot set 'name.first': 'name' is not a nested object, had value: "Ada"`)
+
+	assert.Equal(t, map[string]interface{}{"name": "Hogwarts"}, cp["school"])
+	lat, _ := GetPath(cp, "address.geo.lat")
+	assert.Equal(t, 0.0, lat)
+	// The original Row is unchanged.
+	assert.Equal(t, nestedRow(), row)
+}
+
+func TestFlattenUnflatten(t *testing.T) {
+	flat := optimus.Row{
+		"name":            "Ada",
+		"address.city":    "SF",
+		"address.geo.lat": 37.7,
+		"address.geo.lng": -122.4,
+		"tags":            []interface{}{"a", "b"},
+	}
+	table := optimus.Transform(slice.New([]optimus.Row{nestedRow()}), Flatten())
+	assert.Equal(t, []optimus.Row{flat}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+
+	table = optimus.Transform(optimus.Transform(slice.New([]optimus.Row{nestedRow()}), Flatten()), Unflatten())
+	assert.Equal(t, []optimus.Row{{
+		"name": "Ada",
+		"address": map[string]interface{}{
+			"city": "SF",
+			"geo":  map[string]interface{}{"lat": 37.7, "lng": -122.4},
+		},
+		"tags": []interface{}{"a", "b"},
+	}}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+}
+
+func TestFlattenErrors(t *testing.T) {
+	input := []optimus.Row{{"a.b": 1, "a": map[string]interface{}{"b": 2}}}
+	table := optimus.Transform(slice.New(input), Flatten())
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "flattening produced the key 'a.b' more than once")
+}
+
+func TestUnflattenErrors(t *testing.T) {
+	input := []optimus.Row{{"a": 1, "a.b": 2}}
+	table := optimus.Transform(slice.New(input), Unflatten())
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "cannot set 'a.b': 'a' is not a nested object, had value: 1")
+
+	input = []optimus.Row{{"a": map[string]interface{}{"b": 1}, "a.b": 2}}
+	table = optimus.Transform(slice.New(input), Unflatten())
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "cannot unflatten 'a.b': the key was already set")
+}
+
+func TestExplode(t *testing.T) {
+	input := []optimus.Row{
+		{"id": 1, "tags": []interface{}{"a", "b"}},
+		{"id": 2, "tags": []interface{}{}},
+		{"id": 3, "tags": "c"},
+		{"id": 4, "nested": optimus.Row{"tags": []string{"d", "e"}}},
+	}
+	expected := []optimus.Row{
+		{"id": 1, "tags": "a"},
+		{"id": 1, "tags": "b"},
+		{"id": 3, "tags": "c"},
+		{"id": 4, "nested": optimus.Row{"tags": []string{"d", "e"}}},
+	}
+	table := optimus.Transform(slice.New(input), Explode("tags"))
+	assert.Equal(t, expected, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+
+	table = optimus.Transform(slice.New(input[3:]), Explode("nested.tags"))
+	assert.Equal(t, []optimus.Row{
+		{"id": 4, "nested": optimus.Row{"tags": "d"}},
+		{"id": 4, "nested": optimus.Row{"tags": "e"}},
+	}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+}
+
+func TestDottedPaths(t *testing.T) {
+	table := optimus.Transform(slice.New([]optimus.Row{nestedRow()}),
+		Fieldmap(map[string][]string{"address.city": {"city"}, "address.geo.lat": {"lat"}}))
+	assert.Equal(t, []optimus.Row{{"city": "SF", "lat": 37.7}}, tests.GetRows(table))
+
+	input := nestedRow()
+	table = optimus.Transform(slice.New([]optimus.Row{input}),
+		Valuemap(map[string]map[interface{}]interface{}{"address.city": {"SF": "San Francisco"}}))
+	rows := tests.GetRows(table)
+	city, _ := GetPath(rows[0], "address.city")
+	assert.Equal(t, "San Francisco", city)
+	assert.Equal(t, nestedRow(), input)
+
+	id, err := KeyIdentifier("address.geo.lng")(nestedRow())
+	assert.Nil(t, err)
+	assert.Equal(t, -122.4, id)
+}
@@ -1,18 +1,20 @@
 package transforms
 
 import (
+	"sync"
+
 	"github.com/Clever/optimus/v4"
-	"github.com/facebookgo/errgroup"
 )
 
 // RowIdentifier takes in a row and returns something that uniquely identifies the Row.
 type RowIdentifier func(optimus.Row) (interface{}, error)
 
 // KeyIdentifier is a convenience function that returns a RowIdentifier that identifies the row
-// based on the value of a key in the Row.
+// based on the value of a key in the Row. The key may be a dotted path into nested objects.
 func KeyIdentifier(key string) RowIdentifier {
 	return func(row optimus.Row) (interface{}, error) {
-		return row[key], nil
+		val, _ := GetPath(row, key)
+		return val, nil
 	}
 }
 
@@ -52,19 +54,20 @@ func Pair(rightTable optimus.Table, leftID, rightID RowIdentifier, filterFn func
 	mapResult := make(chan error)
 	go func() {
 		defer close(mapResult)
-		for row := range rightTable.Rows() {
-			id, err := rightID(row)
-			if err != nil {
-				mapResult <- err
-				return
-			}
-			if val := right[id]; val == nil {
-				right[id] = []optimus.Row{}
-				joined[id] = false
+		mapResult <- optimus.Recover(func() error {
+			for row := range rightTable.Rows() {
+				id, err := rightID(row)
+				if err != nil {
+					return err
+				}
+				if val := right[id]; val == nil {
+					right[id] = []optimus.Row{}
+					joined[id] = false
+				}
+				right[id] = append(right[id], row)
 			}
-			right[id] = append(right[id], row)
-		}
-		mapResult <- rightTable.Err()
+			return rightTable.Err()
+		})
 	}()
 
 	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
@@ -74,48 +77,57 @@ func Pair(rightTable optimus.Table, leftID, rightID RowIdentifier, filterFn func
 		// The channel of paired rows from the left and right tables
 		pairedRows := make(chan optimus.Row)
 
-		wg := errgroup.Group{}
+		wg := sync.WaitGroup{}
+		errs := optimus.NewMultiError(0)
 		// Pair the left table with the right table based on the ids
 		wg.Add(1)
 		go func() {
 			defer close(pairedRows)
 			defer wg.Done()
 
-			for leftRow := range in {
-				id, err := leftID(leftRow)
-				if err != nil {
-					wg.Error(err)
-					return
-				}
-				if rightRows := right[id]; rightRows != nil && id != nil {
-					joined[id] = true
-					for _, rightRow := range rightRows {
-						pairedRows <- optimus.Row{"left": leftRow, "right": rightRow}
+			if err := optimus.Recover(func() error {
+				for leftRow := range in {
+					id, err := leftID(leftRow)
+					if err != nil {
+						return err
+					}
+					if rightRows := right[id]; rightRows != nil && id != nil {
+						joined[id] = true
+						for _, rightRow := range rightRows {
+							pairedRows <- optimus.Row{"left": leftRow, "right": rightRow}
+						}
+					} else {
+						pairedRows <- optimus.Row{"left": leftRow}
 					}
-				} else {
-					pairedRows <- optimus.Row{"left": leftRow}
 				}
-			}
 
-			for id, joined := range joined {
-				if joined {
-					continue
-				}
-				for _, rightRow := range right[id] {
-					pairedRows <- optimus.Row{"right": rightRow}
+				for id, joined := range joined {
+					if joined {
+						continue
+					}
+					for _, rightRow := range right[id] {
+						pairedRows <- optimus.Row{"right": rightRow}
+					}
 				}
+				return nil
+			}); err != nil {
+				errs.Add(err)
 			}
-			return
 		}()
 
 		// Filter the paired rows based on our join type
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err := Select(filterFn)(pairedRows, out); err != nil {
-				wg.Error(err)
+			if err := optimus.Recover(func() error {
+				return Select(filterFn)(pairedRows, out)
+			}); err != nil {
+				errs.Add(err)
+				// Let the pairing finish, instead of blocking on the Rows that won't be filtered.
+				drain(pairedRows)
 			}
 		}()
-		return wg.Wait()
+		wg.Wait()
+		return errs.Err()
 	}
 }
@@ -0,0 +1,130 @@
+package transforms
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// PivotAggregator combines all of the values that were found for a single column of a single
+// pivoted Row into the value that ends up in that column. It always receives at least one value.
+type PivotAggregator func(values []interface{}) (interface{}, error)
+
+var (
+	// KeepFirst keeps the first value that was seen for a column.
+	KeepFirst PivotAggregator = func(values []interface{}) (interface{}, error) {
+		return values[0], nil
+	}
+	// KeepLast keeps the last value that was seen for a column.
+	KeepLast PivotAggregator = func(values []interface{}) (interface{}, error) {
+		return values[len(values)-1], nil
+	}
+	// CollectValues keeps every value that was seen for a column as a []interface{}, in the
+	// order they were seen.
+	CollectValues PivotAggregator = func(values []interface{}) (interface{}, error) {
+		return values, nil
+	}
+	// ErrorOnCollision returns an error if more than one value was seen for a column.
+	ErrorOnCollision PivotAggregator = func(values []interface{}) (interface{}, error) {
+		if len(values) > 1 {
+			return nil, fmt.Errorf("found %d values for the same pivoted column: %v", len(values), values)
+		}
+		return values[0], nil
+	}
+)
+
+// Pivot returns a TransformFunc that turns narrow Rows of (id, key, value) into one wide Row per
+// id. Each output Row has the id in the idField and one field per distinct key, set to the
+// aggregated values found for that key. For example, pivoting on "student", "assignment" and
+// "score" turns
+// {"student": "a", "assignment": "hw1", "score": 90} and {"student": "a", "assignment": "hw2", "score": 80}
+// into
+// {"student": "a", "hw1": 90, "hw2": 80}.
+// The aggregator is called once per id and key with every value found for them, so it decides
+// what happens when the same key shows up more than once for an id. Rows missing the idField or
+// keyField result in an error, as do ids that aren't comparable, keys that aren't strings and keys
+// that are the idField, which would overwrite the id.
+// Output Rows are sent in the order their ids were first seen.
+func Pivot(idField, keyField, valueField string, aggregate PivotAggregator) optimus.TransformFunc {
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		ids := []interface{}{}
+		groups := map[interface{}]map[string][]interface{}{}
+		// keys keeps the order columns were seen in for each id, so aggregation is deterministic.
+		keys := map[interface{}][]string{}
+		for row := range in {
+			id, ok := row[idField]
+			if !ok {
+				return fmt.Errorf("could not find id field '%s' in row %v", idField, row)
+			}
+			if id != nil && !reflect.TypeOf(id).Comparable() {
+				return fmt.Errorf("id field '%s' must be comparable, had value: %#v", idField, id)
+			}
+			rawKey, ok := row[keyField]
+			if !ok {
+				return fmt.Errorf("could not find key field '%s' in row %v", keyField, row)
+			}
+			key, ok := rawKey.(string)
+			if !ok {
+				return fmt.Errorf("key field '%s' must be a string, had value: %#v", keyField, rawKey)
+			}
+			if key == idField {
+				return fmt.Errorf("key field '%s' can't have the id field's name '%s'", keyField, idField)
+			}
+			if groups[id] == nil {
+				ids = append(ids, id)
+				groups[id] = map[string][]interface{}{}
+			}
+			if groups[id][key] == nil {
+				keys[id] = append(keys[id], key)
+			}
+			groups[id][key] = append(groups[id][key], row[valueField])
+		}
+		for _, id := range ids {
+			pivoted := optimus.Row{idField: id}
+			for _, key := range keys[id] {
+				val, err := aggregate(groups[id][key])
+				if err != nil {
+					return err
+				}
+				pivoted[key] = val
+			}
+			out <- pivoted
+		}
+		return nil
+	}
+}
+
+// Unpivot returns a TransformFunc that turns each wide Row into one narrow Row per column, also
+// known as a melt. For every column in columns that is present in a Row, an output Row is sent
+// that has all of the fields not in columns, plus the column name in the keyField and the
+// column's value in the valueField. For example, unpivoting the columns "hw1" and "hw2" into
+// "assignment" and "score" turns
+// {"student": "a", "hw1": 90, "hw2": 80}
+// into
+// {"student": "a", "assignment": "hw1", "score": 90} and {"student": "a", "assignment": "hw2", "score": 80}.
+// Output Rows are sent in the order of columns.
+func Unpivot(columns []string, keyField, valueField string) optimus.TransformFunc {
+	unpivoted := map[string]bool{}
+	for _, column := range columns {
+		unpivoted[column] = true
+	}
+	return TableTransform(func(row optimus.Row, out chan<- optimus.Row) error {
+		for _, column := range columns {
+			val, ok := row[column]
+			if !ok {
+				continue
+			}
+			narrow := optimus.Row{}
+			for k, v := range row {
+				if !unpivoted[k] {
+					narrow[k] = v
+				}
+			}
+			narrow[keyField] = column
+			narrow[valueField] = val
+			out <- narrow
+		}
+		return nil
+	})
+}
@@ -0,0 +1,112 @@
+package transforms
+
+import (
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+var gradebook = func() []optimus.Row {
+	return []optimus.Row{
+		{"student": "a", "assignment": "hw1", "score": 90},
+		{"student": "b", "assignment": "hw1", "score": 70},
+		{"student": "a", "assignment": "hw2", "score": 80},
+		{"student": "a", "assignment": "hw1", "score": 95},
+	}
+}
+
+var pivotTests = []struct {
+	desc      string
+	aggregate PivotAggregator
+	expected  []optimus.Row
+}{
+	{
+		desc:      "keep first",
+		aggregate: KeepFirst,
+		expected: []optimus.Row{
+			{"student": "a", "hw1": 90, "hw2": 80},
+			{"student": "b", "hw1": 70},
+		},
+	},
+	{
+		desc:      "keep last",
+		aggregate: KeepLast,
+		expected: []optimus.Row{
+			{"student": "a", "hw1": 95, "hw2": 80},
+			{"student": "b", "hw1": 70},
+		},
+	},
+	{
+		desc:      "collect values",
+		aggregate: CollectValues,
+		expected: []optimus.Row{
+			{"student": "a", "hw1": []interface{}{90, 95}, "hw2": []interface{}{80}},
+			{"student": "b", "hw1": []interface{}{70}},
+		},
+	},
+}
+
+func TestPivot(t *testing.T) {
+	for _, pivotTest := range pivotTests {
+		table := optimus.Transform(slice.New(gradebook()),
+			Pivot("student", "assignment", "score", pivotTest.aggregate))
+		assert.Equal(t, pivotTest.expected, tests.GetRows(table), pivotTest.desc)
+		assert.Nil(t, table.Err(), pivotTest.desc)
+	}
+}
+
+func TestPivotErrors(t *testing.T) {
+	table := optimus.Transform(slice.New(gradebook()),
+		Pivot("student", "assignment", "score", ErrorOnCollision))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "found 2 values for the same pivoted column: [90 95]")
+
+	table = optimus.Transform(slice.New([]optimus.Row{{"student": "a", "score": 1}}),
+		Pivot("student", "assignment", "score", KeepFirst))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "could not find key field 'assignment' in row map[score:1 student:a]")
+
+	table = optimus.Transform(slice.New([]optimus.Row{{"student": "a", "assignment": 1}}),
+		Pivot("student", "assignment", "score", KeepFirst))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "key field 'assignment' must be a string, had value: 1")
+
+	table = optimus.Transform(slice.New([]optimus.Row{{"student": []int{1}, "assignment": "hw1"}}),
+		Pivot("student", "assignment", "score", KeepFirst))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "id field 'student' must be comparable, had value: []int{1}")
+
+	table = optimus.Transform(slice.New([]optimus.Row{{"student": "a", "assignment": "student"}}),
+		Pivot("student", "assignment", "score", KeepFirst))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "key field 'assignment' can't have the id field's name 'student'")
+}
+
+func TestUnpivot(t *testing.T) {
+	input := []optimus.Row{
+		{"student": "a", "hw1": 90, "hw2": 80},
+		{"student": "b", "hw1": 70},
+	}
+	expected := []optimus.Row{
+		{"student": "a", "assignment": "hw1", "score": 90},
+		{"student": "a", "assignment": "hw2", "score": 80},
+		{"student": "b", "assignment": "hw1", "score": 70},
+	}
+	table := optimus.Transform(slice.New(input), Unpivot([]string{"hw1", "hw2"}, "assignment", "score"))
+	assert.Equal(t, expected, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+}
+
+func TestPivotRoundTrip(t *testing.T) {
+	pivoted := optimus.Transform(slice.New(gradebook()[:3]), Pivot("student", "assignment", "score", ErrorOnCollision))
+	table := optimus.Transform(pivoted, Unpivot([]string{"hw1", "hw2"}, "assignment", "score"))
+	assert.Equal(t, []optimus.Row{
+		{"student": "a", "assignment": "hw1", "score": 90},
+		{"student": "a", "assignment": "hw2", "score": 80},
+		{"student": "b", "assignment": "hw1", "score": 70},
+	}, tests.GetRows(table))
+	assert.Nil(t, table.Err())
+}
@@ -0,0 +1,97 @@
+package transforms
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// RateLimiter is a token bucket that limits how often something happens. It's safe to share
+// between goroutines, e.g. between the copies of a function run by Concurrently.
+type RateLimiter struct {
+	clock  Clock
+	rate   float64
+	burst  float64
+	m      sync.Mutex
+	tokens float64
+	last   time.Time
+	// err is returned by every Wait if the RateLimiter was made with a rate that isn't positive.
+	err error
+}
+
+// NewRateLimiter returns a RateLimiter that allows perSecond events a second on average, and up
+// to burst events at once. A nil clock means SystemClock. If perSecond isn't positive, Wait
+// returns an error.
+func NewRateLimiter(perSecond float64, burst int, clock Clock) *RateLimiter {
+	var err error
+	if !(perSecond > 0) {
+		err = fmt.Errorf("NewRateLimiter's rate must be positive, got %v", perSecond)
+	}
+	if clock == nil {
+		clock = SystemClock
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		clock:  clock,
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   clock.Now(),
+		err:    err,
+	}
+}
+
+// Wait blocks until an event is allowed to happen, or the context is done, in which case it
+// returns the context's error. Waiters are allowed through in the order they called Wait.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	if r.err != nil {
+		return r.err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.m.Lock()
+	now := r.clock.Now()
+	if elapsed := now.Sub(r.last); elapsed > 0 {
+		r.tokens = math.Min(r.burst, r.tokens+elapsed.Seconds()*r.rate)
+		r.last = now
+	}
+	// Reserve a token, even if it won't be available until later.
+	r.tokens--
+	wait := time.Duration(0)
+	if r.tokens < 0 {
+		wait = time.Duration(-r.tokens / r.rate * float64(time.Second))
+	}
+	r.m.Unlock()
+	if wait == 0 {
+		return nil
+	}
+	select {
+	case <-r.clock.After(wait):
+		return nil
+	case <-ctx.Done():
+		// Give back the reserved token so that the waiters after this one don't wait for it.
+		r.m.Lock()
+		r.tokens++
+		r.m.Unlock()
+		return ctx.Err()
+	}
+}
+
+// RateLimited wraps a function that's passed to Map so that it's called no more often than the
+// RateLimiter allows. If the context is done while waiting, the context's error is returned.
+func RateLimited(ctx context.Context, limiter *RateLimiter,
+	fn func(optimus.Row) (optimus.Row, error)) func(optimus.Row) (optimus.Row, error) {
+	return func(row optimus.Row) (optimus.Row, error) {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		return fn(row)
+	}
+}
@@ -0,0 +1,126 @@
+package transforms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+)
+
+type permanentError struct {
+	err error
+}
+
+func (p permanentError) Error() string { return p.err.Error() }
+func (p permanentError) Unwrap() error { return p.err }
+
+// Permanent wraps an error so that it's never retried by a RetryPolicy using IsRetryable.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err}
+}
+
+// IsRetryable reports whether an error is worth retrying. Errors are retryable unless they were
+// wrapped with Permanent, or they're a context being canceled or timing out.
+func IsRetryable(err error) bool {
+	var permanent permanentError
+	return !errors.As(err, &permanent) &&
+		!errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// RetryPolicy describes how to retry something that failed, with exponential backoff and jitter.
+// The zero value tries once.
+type RetryPolicy struct {
+	// MaxAttempts is the most times to try. Zero means once.
+	MaxAttempts int
+	// InitialBackoff is how long to wait before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff caps how long to wait between attempts. Zero means there's no cap.
+	MaxBackoff time.Duration
+	// Multiplier is what the backoff is multiplied by after every retry. Zero means 2.
+	Multiplier float64
+	// Jitter randomizes each backoff by up to this fraction of it, in either direction, so that
+	// many retrying callers don't retry in lockstep. It should be between 0 and 1.
+	Jitter float64
+	// Retryable reports whether an error should be retried. Nil means IsRetryable.
+	Retryable func(error) bool
+	// Clock is used to wait between attempts. Nil means SystemClock.
+	Clock Clock
+}
+
+// backoff returns how long to wait after the given attempt, starting at 1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		backoff *= multiplier
+		if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		backoff += backoff * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(backoff)
+}
+
+// Do calls fn until it succeeds, it returns an error that isn't retryable, it has been tried
+// MaxAttempts times, or the context is done. It returns the last error, annotated with the number
+// of attempts if fn was tried more than once, or the context's error.
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	clock := p.Clock
+	if clock == nil {
+		clock = SystemClock
+	}
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := fn()
+		if err == nil || !retryable(err) {
+			return err
+		}
+		if attempt >= p.MaxAttempts {
+			if attempt == 1 {
+				return err
+			}
+			return fmt.Errorf("failed after %d attempts: %w", attempt, err)
+		}
+		select {
+		case <-clock.After(p.backoff(attempt)):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Retrying wraps a function that's passed to Map so that it's retried according to the
+// RetryPolicy. Each attempt is passed the same Row, so the function shouldn't modify it before it
+// succeeds.
+func Retrying(ctx context.Context, policy RetryPolicy,
+	fn func(optimus.Row) (optimus.Row, error)) func(optimus.Row) (optimus.Row, error) {
+	return func(row optimus.Row) (optimus.Row, error) {
+		var out optimus.Row
+		err := policy.Do(ctx, func() error {
+			var err error
+			out, err = fn(row)
+			return err
+		})
+		return out, err
+	}
+}
@@ -0,0 +1,178 @@
+package transforms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a Clock that doesn't wait: After advances the time immediately and records how
+// long it was asked to wait.
+type fakeClock struct {
+	m     sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.now = c.now.Add(d)
+	c.waits = append(c.waits, d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *fakeClock) Waits() []time.Duration {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.waits
+}
+
+func failTimes(n int, err error) (func(optimus.Row) (optimus.Row, error), *int) {
+	calls := 0
+	return func(row optimus.Row) (optimus.Row, error) {
+		calls++
+		if calls <= n {
+			return nil, err
+		}
+		return row, nil
+	}, &calls
+}
+
+func TestRetrying(t *testing.T) {
+	clock := &fakeClock{}
+	policy := RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     300 * time.Millisecond,
+		Clock:          clock,
+	}
+	fn, calls := failTimes(3, errors.New("temporary error"))
+	row, err := Retrying(context.Background(), policy, fn)(optimus.Row{"a": 1})
+	assert.Nil(t, err)
+	assert.Equal(t, optimus.Row{"a": 1}, row)
+	assert.Equal(t, 4, *calls)
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond},
+		clock.Waits())
+}
+
+func TestRetryingGivesUp(t *testing.T) {
+	temporary := errors.New("temporary error")
+	fn, calls := failTimes(10, temporary)
+	_, err := Retrying(context.Background(), RetryPolicy{MaxAttempts: 3, Clock: &fakeClock{}}, fn)(optimus.Row{})
+	assert.EqualError(t, err, "failed after 3 attempts: temporary error")
+	assert.True(t, errors.Is(err, temporary))
+	assert.Equal(t, 3, *calls)
+}
+
+func TestRetryingPermanentErrors(t *testing.T) {
+	permanent := errors.New("permanent error")
+	fn, calls := failTimes(10, Permanent(permanent))
+	_, err := Retrying(context.Background(), RetryPolicy{MaxAttempts: 3, Clock: &fakeClock{}}, fn)(optimus.Row{})
+	assert.EqualError(t, err, "permanent error")
+	assert.True(t, errors.Is(err, permanent))
+	assert.Equal(t, 1, *calls)
+
+	fn, calls = failTimes(10, errors.New("unclassified error"))
+	policy := RetryPolicy{MaxAttempts: 3, Retryable: func(error) bool { return false }, Clock: &fakeClock{}}
+	_, err = Retrying(context.Background(), policy, fn)(optimus.Row{})
+	assert.EqualError(t, err, "unclassified error")
+	assert.Equal(t, 1, *calls)
+}
+
+func TestRetryingContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	fn := func(optimus.Row) (optimus.Row, error) {
+		calls++
+		cancel()
+		return nil, errors.New("temporary error")
+	}
+	_, err := Retrying(ctx, RetryPolicy{MaxAttempts: 3, Clock: &fakeClock{}}, fn)(optimus.Row{})
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		backoff := policy.backoff(1)
+		assert.True(t, backoff >= 50*time.Millisecond && backoff <= 150*time.Millisecond, "backoff %s", backoff)
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	clock := &fakeClock{}
+	limiter := NewRateLimiter(10, 2, clock)
+	fn := RateLimited(context.Background(), limiter, func(row optimus.Row) (optimus.Row, error) {
+		return row, nil
+	})
+	table := optimus.Transform(slice.New(numberedRows(5)), Map(fn))
+	assert.Equal(t, numberedRows(5), tests.GetRows(table))
+	assert.Nil(t, table.Err())
+	// The first two rows use the burst, and the rest wait for a token each.
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond},
+		clock.Waits())
+}
+
+func TestRateLimiterRate(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.NaN()} {
+		limiter := NewRateLimiter(rate, 1, nil)
+		table := optimus.Transform(defaultSource(), Map(RateLimited(context.Background(), limiter,
+			func(row optimus.Row) (optimus.Row, error) { return row, nil })))
+		tests.Consumed(t, table)
+		assert.EqualError(t, table.Err(), fmt.Sprintf("NewRateLimiter's rate must be positive, got %v", rate))
+	}
+}
+
+func TestRateLimitedContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	limiter := NewRateLimiter(1, 1, &fakeClock{})
+	_, err := RateLimited(ctx, limiter, func(row optimus.Row) (optimus.Row, error) {
+		t.Fatal("never expected fn to be called")
+		return row, nil
+	})(optimus.Row{})
+	assert.Equal(t, context.Canceled, err)
+}
+
+// TestRateLimitedRetryingConcurrently tests that the wrappers compose with each other and with
+// Concurrently.
+func TestRateLimitedRetryingConcurrently(t *testing.T) {
+	clock := &fakeClock{}
+	ctx := context.Background()
+	var m sync.Mutex
+	failed := map[interface{}]bool{}
+	// Fail the first attempt for every row.
+	fn := func(row optimus.Row) (optimus.Row, error) {
+		m.Lock()
+		defer m.Unlock()
+		if !failed[row["i"]] {
+			failed[row["i"]] = true
+			return nil, errors.New("temporary error")
+		}
+		return row, nil
+	}
+	limiter := NewRateLimiter(100, 1, clock)
+	wrapped := Retrying(ctx, RetryPolicy{MaxAttempts: 2, Clock: clock}, RateLimited(ctx, limiter, fn))
+	table := optimus.Transform(slice.New(numberedRows(20)), Concurrently(Map(wrapped), 4))
+	assert.Len(t, tests.GetRows(table), 20)
+	assert.Nil(t, table.Err())
+}
@@ -68,11 +68,7 @@ type compressedSorter struct {
 
 func (r *compressedSorter) Len() int { return len(r.rows) }
 
-// NOTE: we cannot test this panic because Optimus transforms operate in a seperate goroutine
-// that is not propagated back up to the main goroutine.
-//
-// > A panic cannot be recovered by a different goroutine.
-// https://github.com/golang/go/wiki/PanicAndRecover
+// Less panics if the keys aren't ints, float64s or strings. optimus.Transform recovers the panic.
 func (r *compressedSorter) Less(i, j int) bool {
 	switch key1 := r.rows[i].key.(type) {
 	case int:
@@ -126,7 +122,8 @@ func compressedSort(sorter func(sort.Interface), getKeyVal RowIdentifier) optimu
 }
 
 // StableCompressedSort sorts an Optimus table based on the provided RowIdentifier. If the
-// RowIdentifier returns values that are not an int, float64 or string, the function will panic.
+// RowIdentifier returns values that are not an int, float64 or string, the function will panic,
+// and the Table fails with an *optimus.PanicError.
 // It outputs the rows in stably sorted order.
 func StableCompressedSort(getKey RowIdentifier) optimus.TransformFunc {
 	return compressedSort(sort.Stable, getKey)
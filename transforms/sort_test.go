@@ -151,3 +151,10 @@ func TestStableCompressed(t *testing.T) {
 	assert.Nil(t, table.Err())
 	assert.Equal(t, actual, stableInput)
 }
+
+func TestStableCompressedBadKeys(t *testing.T) {
+	input := []optimus.Row{{"c": "a"}, {"c": true}}
+	table := optimus.Transform(slice.New(input), StableCompressedSort(KeyIdentifier("c")))
+	tests.HasRows(t, table, 0)
+	assert.IsType(t, &optimus.PanicError{}, table.Err())
+}
@@ -22,52 +22,46 @@ func TableTransform(transform func(optimus.Row, chan<- optimus.Row) error) optim
 
 // Select returns a TransformFunc that removes any rows that don't pass the filter.
 func Select(filter func(optimus.Row) (bool, error)) optimus.TransformFunc {
-	return TableTransform(func(row optimus.Row, out chan<- optimus.Row) error {
-		pass, err := filter(row)
-		if err != nil || !pass {
-			return err
+	return Fuse(SelectStep(filter))
+}
+
+// Validate returns a TransformFunc that removes any rows that fail the check. Unlike Select, it
+// doesn't stop at the first error: it checks every Row, and then returns an *optimus.MultiError of
+// the errors, with up to limit of them. See optimus.CollectErrors for collecting them with the
+// errors of the rest of the pipeline.
+func Validate(check func(optimus.Row) error, limit int) optimus.TransformFunc {
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		errs := optimus.NewMultiError(limit)
+		for row := range in {
+			if err := check(row); err != nil {
+				errs.Add(err)
+				continue
+			}
+			out <- row
 		}
-		out <- row
-		return nil
-	})
+		if errs.Len() == 0 {
+			return nil
+		}
+		return errs
+	}
 }
 
 // Map returns a TransformFunc that transforms every row with the given function.
 func Map(transform func(optimus.Row) (optimus.Row, error)) optimus.TransformFunc {
-	return TableTransform(func(in optimus.Row, out chan<- optimus.Row) error {
-		row, err := transform(in)
-		if err != nil {
-			return err
-		}
-		out <- row
-		return nil
-	})
+	return Fuse(MapStep(transform))
 }
 
 // Each returns a TransformFunc that makes no changes to the table, but calls the given function
 // on every Row.
 func Each(fn func(optimus.Row) error) optimus.TransformFunc {
-	return Map(func(row optimus.Row) (optimus.Row, error) {
-		if err := fn(row); err != nil {
-			return nil, err
-		}
-		return row, nil
-	})
+	return Fuse(EachStep(fn))
 }
 
 // Fieldmap returns a TransformFunc that applies a field mapping to every Row.
+// The keys of the mapping may be dotted paths into nested objects, e.g. "address.city". The
+// fields they're mapped to are always set as top-level keys.
 func Fieldmap(mappings map[string][]string) optimus.TransformFunc {
-	return Map(func(row optimus.Row) (optimus.Row, error) {
-		newRow := optimus.Row{}
-		for key, vals := range mappings {
-			for _, val := range vals {
-				if oldRowVal, ok := row[key]; ok {
-					newRow[val] = oldRowVal
-				}
-			}
-		}
-		return newRow, nil
-	})
+	return Fuse(FieldmapStep(mappings))
 }
 
 // SafeFieldmap returns a TransformFunc that applies a field mapping to every Row.
@@ -77,7 +71,7 @@ func SafeFieldmap(mappings map[string][]string) optimus.TransformFunc {
 		newRow := optimus.Row{}
 		for key, vals := range mappings {
 			for _, val := range vals {
-				if oldRowVal, ok := row[key]; ok {
+				if oldRowVal, ok := GetPath(row, key); ok {
 					if _, ok := newRow[val]; ok {
 						return nil, fmt.Errorf("Detected multiple mappings to the same value for key %s", val)
 					}
@@ -90,35 +84,64 @@ func SafeFieldmap(mappings map[string][]string) optimus.TransformFunc {
 }
 
 // Valuemap returns a TransformFunc that applies a value mapping to every Row.
+// The keys of the mapping may be dotted paths into nested objects, e.g. "address.state".
 func Valuemap(mappings map[string]map[interface{}]interface{}) optimus.TransformFunc {
-	return Map(func(row optimus.Row) (optimus.Row, error) {
-		newRow := optimus.Row{}
-		for key, val := range row {
-			if mappings[key] == nil || mappings[key][val] == nil {
-				newRow[key] = val
-				continue
-			}
-			newRow[key] = mappings[key][val]
-		}
-		return newRow, nil
-	})
+	return Fuse(ValuemapStep(mappings))
 }
 
 type joinStruct struct {
-	Left, Inner joinType
+	Left, Inner, Right, Outer, Semi, Anti joinType
 }
 
 type joinType struct {
 	int
 }
 
+// JoinKind is the type of the join types in JoinType, for functions that take one as an argument.
+type JoinKind = joinType
+
+var joinNames = []string{"Left", "Inner", "Right", "Outer", "Semi", "Anti"}
+
+// String returns the name of the join type, e.g. "Left".
+func (j joinType) String() string {
+	if j.int < 0 || j.int >= len(joinNames) {
+		return fmt.Sprintf("joinType(%d)", j.int)
+	}
+	return joinNames[j.int]
+}
+
 // JoinType describes the type of join.
 // Left: Always add row from Left table, even if no corresponding rows found in Right table)
 // Inner: Only add row from Left table if corresponding row(s) found in Right table)
-var JoinType = joinStruct{Left: joinType{0}, Inner: joinType{1}}
+// Right: Always add row from Right table, even if no corresponding rows found in Left table)
+// Outer: Always add rows from both tables, merging the ones that correspond)
+// Semi: Add row from Left table once, unmerged, if corresponding row(s) found in Right table)
+// Anti: Add row from Left table, unmerged, only if no corresponding rows found in Right table)
+var JoinType = joinStruct{
+	Left:  joinType{0},
+	Inner: joinType{1},
+	Right: joinType{2},
+	Outer: joinType{3},
+	Semi:  joinType{4},
+	Anti:  joinType{5},
+}
 
 // Join returns a TransformFunc that joins Rows with another table using the specified join type.
+// The fields of the right row overwrite the fields of the left row.
 func Join(rightTable optimus.Table, leftHeader string, rightHeader string, join joinType) optimus.TransformFunc {
+	return JoinBy(rightTable, KeyIdentifier(leftHeader), KeyIdentifier(rightHeader), join, nil)
+}
+
+// JoinBy returns a TransformFunc that joins Rows with another table using the specified identifier
+// functions and join type. Corresponding rows are combined with the MergeFunc, which gets a nil
+// Row for the missing side of Left, Right and Outer joins. A nil MergeFunc means RightWins.
+func JoinBy(rightTable optimus.Table, leftID, rightID RowIdentifier, join joinType, merge MergeFunc) optimus.TransformFunc {
+	switch join {
+	case JoinType.Semi:
+		return filterByMatch(rightTable, leftID, rightID, true)
+	case JoinType.Anti:
+		return filterByMatch(rightTable, leftID, rightID, false)
+	}
 	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
 		var filterFn func(optimus.Row) (bool, error)
 		switch join {
@@ -126,37 +149,63 @@ func Join(rightTable optimus.Table, leftHeader string, rightHeader string, join
 			filterFn = LeftJoin
 		case JoinType.Inner:
 			filterFn = InnerJoin
+		case JoinType.Right:
+			filterFn = RightJoin
+		case JoinType.Outer:
+			filterFn = OuterJoin
 		}
 
 		unmergedOut := make(chan optimus.Row)
-		pairer := Pair(rightTable, KeyIdentifier(leftHeader), KeyIdentifier(rightHeader), filterFn)
+		pairer := Pair(rightTable, leftID, rightID, filterFn)
 
 		errs := make(chan error, 1)
 
 		go func() {
 			defer close(unmergedOut)
 			defer close(errs)
-			errs <- pairer(in, unmergedOut)
+			errs <- optimus.Recover(func() error {
+				return pairer(in, unmergedOut)
+			})
 		}()
 		for row := range unmergedOut {
-			out <- mergePairs(row)
+			if merge == nil {
+				out <- mergePairs(row)
+				continue
+			}
+			left, _ := row["left"].(optimus.Row)
+			right, _ := row["right"].(optimus.Row)
+			merged, err := merge(left, right)
+			if err != nil {
+				drain(unmergedOut)
+				<-errs
+				return err
+			}
+			out <- merged
 		}
 		return <-errs
 	}
 }
 
 func mergePairs(pairs optimus.Row) optimus.Row {
-	// Assume left exists because that's how Join uses it
-	left := pairs["left"].(optimus.Row)
-	right := pairs["right"]
+	left, _ := pairs["left"].(optimus.Row)
+	right, _ := pairs["right"].(optimus.Row)
+	return mergeRows(left, right)
+}
+
+// mergeRows returns a Row with the fields of both Rows, where the right Row's fields overwrite
+// the left Row's. If either Row is nil, the other Row is returned.
+func mergeRows(left, right optimus.Row) optimus.Row {
 	if right == nil {
 		return left
 	}
+	if left == nil {
+		return right
+	}
 	output := optimus.Row{}
 	for k, v := range left {
 		output[k] = v
 	}
-	for k, v := range right.(optimus.Row) {
+	for k, v := range right {
 		output[k] = v
 	}
 	return output
@@ -177,34 +226,71 @@ func Reduce(fn func(accum, item optimus.Row) error) optimus.TransformFunc {
 }
 
 // Concurrently returns a TransformFunc that applies the given TransformFunc a number of times
-// concurrently, based on the supplied concurrency count.
+// concurrently, based on the supplied concurrency count. Once one of them fails, the others stop
+// receiving Rows, and it returns after all of them have returned. If more than one failed, it
+// returns an *optimus.MultiError of all their errors.
 func Concurrently(fn optimus.TransformFunc, concurrency int) optimus.TransformFunc {
 	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		// The workers receive from work instead of in, so that they can be cut off after an error
+		// without receiving the rest of the Rows.
+		work := make(chan optimus.Row)
+		stop := make(chan struct{})
+		var stopOnce sync.Once
+		stopWork := func() { stopOnce.Do(func() { close(stop) }) }
+		go func() {
+			defer close(work)
+			for row := range in {
+				select {
+				case work <- row:
+				case <-stop:
+					return
+				}
+			}
+		}()
+
 		wg := sync.WaitGroup{}
 		wg.Add(concurrency)
-		errs := make(chan error)
+		errs := optimus.NewMultiError(0)
 		for i := 0; i < concurrency; i++ {
 			go func() {
 				defer wg.Done()
-				if err := fn(in, out); err != nil {
-					errs <- err
+				if err := optimus.Recover(func() error {
+					return fn(work, out)
+				}); err != nil {
+					errs.Add(err)
+					stopWork()
 				}
 			}()
 		}
-		go func() {
-			wg.Wait()
-			close(errs)
-		}()
-		for err := range errs {
-			return err
-		}
-		return nil
+		wg.Wait()
+		// The workers may have returned without receiving every Row.
+		stopWork()
+		return errs.Err()
 	}
 }
 
 // Concat returns a TransformFunc that concatenates all the Rows in the input Tables, in order.
+// If any of the Tables fails, the ones after it are stopped. If the Table that Concat's output
+// goes to is stopped, the input Tables are stopped too.
 func Concat(tables ...optimus.Table) optimus.TransformFunc {
 	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		done := make(chan struct{})
+		defer func() {
+			close(done)
+			for _, table := range tables {
+				table.Stop()
+				drain(table.Rows())
+			}
+		}()
+		go func() {
+			select {
+			case <-optimus.Stopped(out):
+				for _, table := range tables {
+					table.Stop()
+				}
+			case <-done:
+			}
+		}()
 		for row := range in {
 			out <- row
 		}
@@ -222,19 +308,23 @@ func Concat(tables ...optimus.Table) optimus.TransformFunc {
 
 // Unique returns a TransformFunc that returns Rows that are unique, according to the specified hash.
 // No order is guaranteed for the unique row which is returned.
+// Every hash is kept in memory. A new set of hashes is created each time the TransformFunc runs.
+// See SpillingUnique and BloomUnique for tables that don't fit in memory.
 func Unique(hash RowIdentifier) optimus.TransformFunc {
-	set := set.New()
-	return Select(func(row optimus.Row) (bool, error) {
-		hashedRow, err := hash(row)
-		if err != nil {
-			return false, err
-		}
-		if !set.Has(hashedRow) {
-			set.Add(hashedRow)
-			return true, nil
-		}
-		return false, nil
-	})
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		set := set.New()
+		return Select(func(row optimus.Row) (bool, error) {
+			hashedRow, err := hash(row)
+			if err != nil {
+				return false, err
+			}
+			if !set.Has(hashedRow) {
+				set.Add(hashedRow)
+				return true, nil
+			}
+			return false, nil
+		})(in, out)
+	}
 }
 
 // GroupBy returns a TransformFunc that returns Rows of Rows grouped by their identifier.
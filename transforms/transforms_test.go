@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/Clever/optimus/v4"
@@ -169,6 +170,8 @@ var transformEqualities = []tests.TableCompareConfig{
 				{"header1": "value5", "header2": "value6"},
 			})
 		},
+		// Concurrently doesn't preserve the order of Rows.
+		Comparison: tests.Comparison{Mode: tests.Unordered},
 	},
 	{
 		Name: "Reduce",
@@ -301,6 +304,63 @@ func TestJoinErrors(t *testing.T) {
 	assert.EqualError(t, table.Err(), "garbage error")
 }
 
+func TestValidate(t *testing.T) {
+	odd := func(row optimus.Row) error {
+		if row["i"].(int)%2 == 0 {
+			return fmt.Errorf("%d is even", row["i"])
+		}
+		return nil
+	}
+	table := optimus.Transform(slice.New(numberedRows(10)), Validate(odd, 3), optimus.CollectErrors(0))
+	assert.Equal(t, []optimus.Row{{"i": 1}, {"i": 3}, {"i": 5}, {"i": 7}, {"i": 9}}, tests.GetRows(table))
+	if assert.IsType(t, &optimus.MultiError{}, table.Err()) {
+		errs := table.Err().(*optimus.MultiError)
+		assert.Equal(t, 5, errs.Len())
+		assert.Equal(t, "5 errors: 0 is even; 2 is even; 4 is even; and 2 more", errs.Error())
+	}
+
+	table = optimus.Transform(slice.New(numberedRows(10)), Validate(func(optimus.Row) error {
+		return nil
+	}, 3))
+	tests.HasRows(t, table, 10)
+	assert.Nil(t, table.Err())
+}
+
+// TestConcurrentlyErrors tests that Concurrently returns the errors of all of its workers, after
+// they've all returned.
+func TestConcurrentlyErrors(t *testing.T) {
+	failed := errors.New("failed")
+	var started, returned sync.WaitGroup
+	started.Add(4)
+	worker := func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		defer returned.Done()
+		<-in
+		// Wait for every worker to have a Row, so that they all fail.
+		started.Done()
+		started.Wait()
+		return failed
+	}
+	returned.Add(4)
+	in := infinite.New()
+	err := discard.Discard(optimus.Transform(in, Concurrently(worker, 4)))
+	returned.Wait()
+	if assert.IsType(t, &optimus.MultiError{}, err) {
+		assert.Equal(t, 4, err.(*optimus.MultiError).Len())
+		assert.True(t, errors.Is(err, failed))
+	}
+	tests.Consumed(t, in)
+
+	// A single error is returned as is.
+	table := optimus.Transform(slice.New(numberedRows(10)), Concurrently(Map(func(row optimus.Row) (optimus.Row, error) {
+		if row["i"] == 5 {
+			return nil, failed
+		}
+		return row, nil
+	}), 1))
+	tests.GetRows(table)
+	assert.Equal(t, failed, table.Err())
+}
+
 func hashByHeader(row optimus.Row, header string) (interface{}, error) {
 	val, ok := row[header]
 	if !ok {
@@ -411,6 +471,24 @@ func TestGroupBy(t *testing.T) {
 	assert.Equal(t, expected, sortByGroup(actual))
 }
 
+// TestGroupByKeyed tests GroupBy by comparing its groups by their ids, since their order isn't
+// guaranteed.
+func TestGroupByKeyed(t *testing.T) {
+	input := []optimus.Row{
+		{"group": "a", "key": 1},
+		{"group": "b", "key": 2},
+		{"group": "a", "key": 3},
+	}
+	expected := []optimus.Row{
+		{"id": "a", "rows": []optimus.Row{input[0], input[2]}},
+		{"id": "b", "rows": []optimus.Row{input[1]}},
+	}
+	table := optimus.Transform(slice.New(input), GroupBy(KeyIdentifier("group")))
+	tests.CompareRows(t, expected, tests.GetRows(table),
+		tests.Comparison{Mode: tests.Keyed, Key: KeyIdentifier("id")})
+	assert.Nil(t, table.Err())
+}
+
 type multiHeader struct {
 	val1 interface{}
 	val2 interface{}
@@ -471,6 +549,32 @@ func TestTransformError(t *testing.T) {
 	}
 }
 
+// TestTransformPanic tests that the panics of transforms, and of the goroutines that they start,
+// fail the Table instead of crashing.
+func TestTransformPanic(t *testing.T) {
+	panics := func(optimus.Row) (optimus.Row, error) {
+		panic("bad row")
+	}
+	panickingID := func(optimus.Row) (interface{}, error) {
+		panic("bad row")
+	}
+	for name, transform := range map[string]optimus.TransformFunc{
+		"Map":          Map(panics),
+		"Concurrently": Concurrently(Map(panics), 3),
+		"Pair":         Pair(defaultSource(), panickingID, panickingID, InnerJoin),
+		"JoinBy":       JoinBy(defaultSource(), panickingID, KeyIdentifier("header1"), JoinType.Inner, nil),
+	} {
+		in := infinite.New()
+		err := discard.Discard(optimus.Transform(in, transform))
+		// Concurrently returns the panics of each of its workers.
+		var panicErr *optimus.PanicError
+		if assert.True(t, errors.As(err, &panicErr), name) {
+			assert.Equal(t, "bad row", panicErr.Value, name)
+		}
+		tests.Consumed(t, in)
+	}
+}
+
 func TestBypassTransforms(t *testing.T) {
 	tbl := slice.New([]optimus.Row{
 		{"a": 1},
@@ -523,3 +627,71 @@ func TestBypassTransforms(t *testing.T) {
 `
 	assert.Equal(t, expectedResult, buf.String())
 }
+
+func TestConformance(t *testing.T) {
+	byI := KeyIdentifier("i")
+	rightTable := func() optimus.Table {
+		return slice.New([]optimus.Row{{"i": 1, "right": true}, {"i": 2, "right": true}})
+	}
+	conformanceTests := map[string]func() optimus.TransformFunc{
+		"Map": func() optimus.TransformFunc {
+			return Map(func(row optimus.Row) (optimus.Row, error) { return row, nil })
+		},
+		"Select": func() optimus.TransformFunc {
+			return Select(func(row optimus.Row) (bool, error) { return row["i"].(int)%2 == 0, nil })
+		},
+		"Fieldmap": func() optimus.TransformFunc {
+			return Fieldmap(map[string][]string{"i": {"j"}})
+		},
+		"Fuse": func() optimus.TransformFunc {
+			return Fuse(
+				MapStep(func(row optimus.Row) (optimus.Row, error) { return row, nil }),
+				SelectStep(func(row optimus.Row) (bool, error) { return row["i"].(int)%2 == 0, nil }),
+			)
+		},
+		"Concat":          func() optimus.TransformFunc { return Concat(rightTable(), rightTable()) },
+		"Concat infinite": func() optimus.TransformFunc { return Concat(rightTable(), infinite.New()) },
+		"Concurrently": func() optimus.TransformFunc {
+			return Concurrently(Each(func(optimus.Row) error { return nil }), 4)
+		},
+		"Reduce": func() optimus.TransformFunc {
+			return Reduce(func(accum, item optimus.Row) error { return nil })
+		},
+		"GroupBy": func() optimus.TransformFunc { return GroupBy(byI) },
+		"Unique":  func() optimus.TransformFunc { return Unique(byI) },
+		"Join": func() optimus.TransformFunc {
+			return JoinBy(rightTable(), byI, byI, JoinType.Outer, nil)
+		},
+		"CrossJoin": func() optimus.TransformFunc { return CrossJoin(rightTable(), nil) },
+		"Sort": func() optimus.TransformFunc {
+			return Sort(func(i, j optimus.Row) (bool, error) { return i["i"].(int) > j["i"].(int), nil })
+		},
+		"Limit": func() optimus.TransformFunc { return Limit(5) },
+		"Batch": func() optimus.TransformFunc { return Batch(10, 0) },
+		"Error": func() optimus.TransformFunc {
+			return TableTransform(func(optimus.Row, chan<- optimus.Row) error {
+				return errors.New("some error")
+			})
+		},
+	}
+	for name, transform := range conformanceTests {
+		t.Run(name, func(t *testing.T) {
+			tests.Conformance(t, func(upstream optimus.Table) optimus.Table {
+				return optimus.Transform(upstream, transform())
+			})
+		})
+		t.Run(name+" with buffers", func(t *testing.T) {
+			tests.Conformance(t, func(upstream optimus.Table) optimus.Table {
+				return optimus.Transform(upstream, transform(), optimus.BufferSize(16))
+			})
+		})
+	}
+	t.Run("FuseBatches", func(t *testing.T) {
+		tests.Conformance(t, func(upstream optimus.Table) optimus.Table {
+			return optimus.Unbatched(optimus.TransformBatches(optimus.Batched(upstream, 7), FuseBatches(
+				MapStep(func(row optimus.Row) (optimus.Row, error) { return row, nil }),
+				SelectStep(func(row optimus.Row) (bool, error) { return row["i"].(int)%2 == 0, nil }),
+			)))
+		})
+	})
+}
@@ -0,0 +1,347 @@
+package transforms
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/Clever/optimus/v4"
+)
+
+// digestSize is the number of bytes of a hash's digest that are kept. 128 bits makes collisions
+// vanishingly unlikely, even for billions of rows.
+const digestSize = 16
+
+type digest [digestSize]byte
+
+// digestOf turns the value returned by a RowIdentifier into a fixed-size digest that can be
+// written to disk. Values are compared by their Go-syntax representation, so they must format
+// the same way every time they're equal (e.g. they shouldn't contain pointers).
+func digestOf(val interface{}) digest {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%T:%#v", val, val)))
+	var d digest
+	copy(d[:], sum[:digestSize])
+	return d
+}
+
+func digestLess(a, b digest) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+// indexStride is the number of digests in each block of a run. A run keeps the first digest of
+// every block in memory, so a lookup reads a single block from disk.
+const indexStride = 256
+
+// runFalsePositiveRate is the false positive rate of each run's Bloom filter, which is the fraction
+// of lookups of new digests that read from the run.
+const runFalsePositiveRate = 0.01
+
+// run is a sorted temporary file of digests, with a Bloom filter and a sparse index of its digests
+// that let most lookups skip reading it.
+type run struct {
+	f      *os.File
+	n      int
+	index  []digest
+	filter *bloomFilter
+}
+
+// contains reports whether the run has the digest. buf must have room for a block of digests.
+func (r *run) contains(d digest, buf []byte) (bool, error) {
+	if !r.filter.has(d) {
+		return false, nil
+	}
+	// The digest can only be in the last block that starts at or before it.
+	block := sort.Search(len(r.index), func(i int) bool { return digestLess(d, r.index[i]) }) - 1
+	if block < 0 {
+		return false, nil
+	}
+	count := min(indexStride, r.n-block*indexStride)
+	buf = buf[:count*digestSize]
+	if _, err := r.f.ReadAt(buf, int64(block*indexStride*digestSize)); err != nil {
+		return false, err
+	}
+	at := func(i int) []byte { return buf[i*digestSize : (i+1)*digestSize] }
+	i := sort.Search(count, func(i int) bool { return bytes.Compare(at(i), d[:]) >= 0 })
+	return i < count && bytes.Equal(at(i), d[:]), nil
+}
+
+// spillingSet is a set of digests that keeps at most max digests in memory. When it's full, the
+// digests in memory are written to a sorted temporary file (a run). Runs are merged like a binary
+// counter: whenever the newest run is at least as large as the one before it, the two are merged,
+// so there are only logarithmically many runs, and each digest is rewritten logarithmically many
+// times.
+type spillingSet struct {
+	dir  string
+	max  int
+	mem  map[digest]struct{}
+	runs []*run
+	buf  []byte
+}
+
+func newSpillingSet(max int, dir string) *spillingSet {
+	return &spillingSet{
+		dir: dir,
+		max: max,
+		mem: map[digest]struct{}{},
+		buf: make([]byte, indexStride*digestSize),
+	}
+}
+
+// add adds the digest to the set, and reports whether it was not already in the set.
+func (s *spillingSet) add(d digest) (bool, error) {
+	if _, ok := s.mem[d]; ok {
+		return false, nil
+	}
+	for _, run := range s.runs {
+		found, err := run.contains(d, s.buf)
+		if err != nil || found {
+			return false, err
+		}
+	}
+	s.mem[d] = struct{}{}
+	if len(s.mem) >= s.max {
+		if err := s.spill(); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (s *spillingSet) spill() error {
+	digests := make([]digest, 0, len(s.mem))
+	for d := range s.mem {
+		digests = append(digests, d)
+	}
+	sort.Slice(digests, func(i, j int) bool { return digestLess(digests[i], digests[j]) })
+	i := 0
+	run, err := s.writeRun(len(digests), func() (digest, error) {
+		i++
+		return digests[i-1], nil
+	})
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, run)
+	s.mem = map[digest]struct{}{}
+	for len(s.runs) > 1 && s.runs[len(s.runs)-2].n <= s.runs[len(s.runs)-1].n {
+		if err := s.mergeLast(2); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeLast merges the last count runs into a single sorted run. A digest is only ever in one
+// run, so the merged run has all of their digests.
+func (s *spillingSet) mergeLast(count int) error {
+	runs := s.runs[len(s.runs)-count:]
+	readers := make([]*bufio.Reader, len(runs))
+	heads := make([]*digest, len(runs))
+	read := func(i int) error {
+		var d digest
+		if _, err := io.ReadFull(readers[i], d[:]); err == io.EOF {
+			heads[i] = nil
+			return nil
+		} else if err != nil {
+			return err
+		}
+		heads[i] = &d
+		return nil
+	}
+	n := 0
+	for i, run := range runs {
+		readers[i] = bufio.NewReader(io.NewSectionReader(run.f, 0, int64(run.n*digestSize)))
+		if err := read(i); err != nil {
+			return err
+		}
+		n += run.n
+	}
+	merged, err := s.writeRun(n, func() (digest, error) {
+		min := -1
+		for i, head := range heads {
+			if head != nil && (min == -1 || digestLess(*head, *heads[min])) {
+				min = i
+			}
+		}
+		d := *heads[min]
+		return d, read(min)
+	})
+	if err != nil {
+		return err
+	}
+	for _, run := range runs {
+		if err := removeFile(run.f); err != nil {
+			removeFile(merged.f)
+			return err
+		}
+	}
+	s.runs = append(s.runs[:len(s.runs)-count], merged)
+	return nil
+}
+
+// writeRun writes a run of the n digests that next returns, in order.
+func (s *spillingSet) writeRun(n int, next func() (digest, error)) (*run, error) {
+	f, err := os.CreateTemp(s.dir, "optimus-unique-")
+	if err != nil {
+		return nil, err
+	}
+	r := &run{f: f, n: n, filter: newBloomFilter(n, runFalsePositiveRate)}
+	w := bufio.NewWriter(f)
+	for i := 0; i < n; i++ {
+		d, err := next()
+		if err == nil {
+			_, err = w.Write(d[:])
+		}
+		if err != nil {
+			removeFile(f)
+			return nil, err
+		}
+		if i%indexStride == 0 {
+			r.index = append(r.index, d)
+		}
+		r.filter.add(d)
+	}
+	if err := w.Flush(); err != nil {
+		removeFile(f)
+		return nil, err
+	}
+	return r, nil
+}
+
+// close removes all of the set's temporary files.
+func (s *spillingSet) close() error {
+	var firstErr error
+	for _, run := range s.runs {
+		if err := removeFile(run.f); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	s.runs = nil
+	return firstErr
+}
+
+func removeFile(f *os.File) error {
+	closeErr := f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// SpillingUnique returns a TransformFunc that returns Rows that are unique, according to the
+// specified hash, while keeping at most maxInMemory hashes in memory. Once that many hashes are
+// in memory they are sorted and written to a temporary file in dir (or the default directory for
+// temporary files if dir is empty), and later Rows are checked against those files as well.
+// For n Rows, there are at most log2(n/maxInMemory)+1 files, and each hash is rewritten about
+// that many times as the files are merged. A Bloom filter and a sparse index of each file are kept
+// in memory, which take about 10 bits per hash on disk. With them, a new Row only reads from about
+// 1% of the files, and a duplicate reads a single 4KB block.
+// Hashes are compared by a 128-bit digest of their Go-syntax representation (see fmt's %#v), so
+// they must format the same way every time they're equal. The temporary files are removed when
+// the TransformFunc returns. A new set of hashes is created each time the TransformFunc runs.
+func SpillingUnique(hash RowIdentifier, maxInMemory int, dir string) optimus.TransformFunc {
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) (err error) {
+		if maxInMemory < 1 {
+			return fmt.Errorf("SpillingUnique must keep at least one hash in memory, got %d", maxInMemory)
+		}
+		set := newSpillingSet(maxInMemory, dir)
+		defer func() {
+			if closeErr := set.close(); err == nil {
+				err = closeErr
+			}
+		}()
+		return Select(func(row optimus.Row) (bool, error) {
+			hashedRow, err := hash(row)
+			if err != nil {
+				return false, err
+			}
+			return set.add(digestOf(hashedRow))
+		})(in, out)
+	}
+}
+
+// bloomFilter is a fixed-size probabilistic set of digests. It never reports that a digest it has
+// seen is new, but may report that a new digest has already been seen.
+type bloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+// newBloomFilter returns a Bloom filter sized so that, after n digests have been added, the
+// probability that a new digest is reported as seen is p.
+func newBloomFilter(n int, p float64) *bloomFilter {
+	size := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if size < 64 {
+		size = 64
+	}
+	hashes := uint64(math.Round(float64(size) / float64(n) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &bloomFilter{bits: make([]uint64, (size+63)/64), size: size, hashes: hashes}
+}
+
+// add adds the digest to the filter, and reports whether it was definitely not already in the
+// filter.
+func (b *bloomFilter) add(d digest) bool {
+	added := false
+	b.bitsOf(d, func(word int, mask uint64) {
+		if b.bits[word]&mask == 0 {
+			b.bits[word] |= mask
+			added = true
+		}
+	})
+	return added
+}
+
+// has reports whether the digest may be in the filter.
+func (b *bloomFilter) has(d digest) bool {
+	has := true
+	b.bitsOf(d, func(word int, mask uint64) {
+		has = has && b.bits[word]&mask != 0
+	})
+	return has
+}
+
+// bitsOf calls fn with each of the digest's bits. The digest is already uniformly distributed, so
+// its two halves are used with double hashing in place of independent hash functions.
+func (b *bloomFilter) bitsOf(d digest, fn func(word int, mask uint64)) {
+	h1 := binary.LittleEndian.Uint64(d[:8])
+	h2 := binary.LittleEndian.Uint64(d[8:]) | 1
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.size
+		fn(int(bit/64), uint64(1)<<(bit%64))
+	}
+}
+
+// BloomUnique returns a TransformFunc that returns Rows that are unique, according to the
+// specified hash, using a fixed amount of memory. The memory is sized so that, once expectedRows
+// unique Rows have been seen, a new Row is wrongly dropped as a duplicate with probability
+// falsePositiveRate. Duplicates are never returned. Hashes are compared by a 128-bit digest of
+// their Go-syntax representation (see fmt's %#v), so they must format the same way every time
+// they're equal. A new filter is created each time the TransformFunc runs.
+func BloomUnique(hash RowIdentifier, expectedRows int, falsePositiveRate float64) optimus.TransformFunc {
+	return func(in <-chan optimus.Row, out chan<- optimus.Row) error {
+		if expectedRows < 1 {
+			return fmt.Errorf("BloomUnique must expect at least one row, got %d", expectedRows)
+		}
+		if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+			return fmt.Errorf("BloomUnique's false positive rate must be between 0 and 1, got %v", falsePositiveRate)
+		}
+		filter := newBloomFilter(expectedRows, falsePositiveRate)
+		return Select(func(row optimus.Row) (bool, error) {
+			hashedRow, err := hash(row)
+			if err != nil {
+				return false, err
+			}
+			return filter.add(digestOf(hashedRow)), nil
+		})(in, out)
+	}
+}
@@ -0,0 +1,108 @@
+package transforms
+
+import (
+	"os"
+	"testing"
+
+	"github.com/Clever/optimus/v4"
+	"github.com/Clever/optimus/v4/sources/slice"
+	"github.com/Clever/optimus/v4/tests"
+	"github.com/stretchr/testify/assert"
+)
+
+// duplicatedRows returns n distinct rows, each repeated after all of them have been seen once.
+func duplicatedRows(n int) ([]optimus.Row, []optimus.Row) {
+	unique := []optimus.Row{}
+	for i := 0; i < n; i++ {
+		unique = append(unique, optimus.Row{"id": i})
+	}
+	input := append(append([]optimus.Row{}, unique...), unique...)
+	return input, unique
+}
+
+func TestUniqueStateIsPerRun(t *testing.T) {
+	input, expected := duplicatedRows(3)
+	unique := Unique(KeyIdentifier("id"))
+	for i := 0; i < 2; i++ {
+		table := optimus.Transform(slice.New(input), unique)
+		assert.Equal(t, expected, tests.GetRows(table))
+		assert.Nil(t, table.Err())
+	}
+}
+
+func TestSpillingUnique(t *testing.T) {
+	// Spilling every 2 rows makes 50 runs, which exercises merging runs.
+	input, expected := duplicatedRows(100)
+	for _, maxInMemory := range []int{1, 2, 1000} {
+		dir := t.TempDir()
+		unique := SpillingUnique(KeyIdentifier("id"), maxInMemory, dir)
+		for i := 0; i < 2; i++ {
+			table := optimus.Transform(slice.New(input), unique)
+			assert.Equal(t, expected, tests.GetRows(table), "maxInMemory %d", maxInMemory)
+			assert.Nil(t, table.Err())
+
+			files, err := os.ReadDir(dir)
+			assert.Nil(t, err)
+			assert.Empty(t, files, "expected temporary files to be removed")
+		}
+	}
+}
+
+// TestSpillingSet tests that a spillingSet finds digests in runs with more than one block, and
+// merges its runs so that there are only logarithmically many of them.
+func TestSpillingSet(t *testing.T) {
+	set := newSpillingSet(10, t.TempDir())
+	defer set.close()
+	for i := 0; i < 5000; i++ {
+		added, err := set.add(digestOf(i))
+		assert.Nil(t, err)
+		assert.True(t, added, "expected %d to be new", i)
+		if i%7 == 0 {
+			added, err = set.add(digestOf(i / 2))
+			assert.Nil(t, err)
+			assert.False(t, added, "expected %d to be a duplicate", i/2)
+		}
+	}
+	assert.LessOrEqual(t, len(set.runs), 10)
+	total := len(set.mem)
+	for _, run := range set.runs {
+		total += run.n
+	}
+	assert.Equal(t, 5000, total)
+}
+
+func TestSpillingUniqueErrors(t *testing.T) {
+	table := optimus.Transform(defaultSource(), SpillingUnique(KeyIdentifier("header1"), 0, ""))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "SpillingUnique must keep at least one hash in memory, got 0")
+
+	table = optimus.Transform(defaultSource(), SpillingUnique(invalidHeaderHash, 1, t.TempDir()))
+	tests.Consumed(t, table)
+	assert.NotNil(t, table.Err())
+}
+
+func TestBloomUnique(t *testing.T) {
+	input, expected := duplicatedRows(1000)
+	table := optimus.Transform(slice.New(input), BloomUnique(KeyIdentifier("id"), 1000, 0.01))
+	actual := tests.GetRows(table)
+	assert.Nil(t, table.Err())
+
+	// Rows may be wrongly dropped, but never duplicated.
+	seen := map[interface{}]bool{}
+	for _, row := range actual {
+		assert.False(t, seen[row["id"]], "duplicate row %v", row)
+		seen[row["id"]] = true
+	}
+	assert.True(t, len(actual) <= len(expected))
+	assert.True(t, len(actual) >= 970, "expected about 1%% of rows to be dropped, got %d rows", len(actual))
+}
+
+func TestBloomUniqueErrors(t *testing.T) {
+	table := optimus.Transform(defaultSource(), BloomUnique(KeyIdentifier("header1"), 0, 0.01))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "BloomUnique must expect at least one row, got 0")
+
+	table = optimus.Transform(defaultSource(), BloomUnique(KeyIdentifier("header1"), 10, 1))
+	tests.Consumed(t, table)
+	assert.EqualError(t, table.Err(), "BloomUnique's false positive rate must be between 0 and 1, got 1")
+}